@@ -1,6 +1,8 @@
 package ant
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"main/gp"
@@ -144,6 +146,12 @@ func (m *Matrix) Copy() Matrix {
 
 }
 
+// evalBudget bounds the compilation and the runs of a program during an
+// evaluation, each run spends at least one move unless the program is broken.
+// There is no deadline, a timeout would make the results depend on the speed
+// of the machine.
+const evalBudget = 10000
+
 type Ant struct {
 	maxMoves       int
 	moves          int
@@ -161,7 +169,7 @@ func NewAnt(maxMoves int, matrix Matrix) *Ant {
 		moves:          0,
 		eaten:          0,
 		dir:            1,
-		matrix:         matrix,
+		matrix:         matrix.Copy(),
 		row:            matrix.StartRow,
 		col:            matrix.StartCol,
 		originalMatrix: matrix.Copy(),
@@ -271,40 +279,85 @@ func ParseMatrix(input string) (Matrix, error) {
 	return ret, nil
 }
 
-func eval(ant *Ant, ind gp.Individual) {
+func antOf(env any) (*Ant, error) {
+	ant, ok := env.(*Ant)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("the environment has to be an *Ant, got %T", env))
+	}
+	return ant, nil
+}
+
+// envPrimitive adapts a method of the ant evaluating the program to a
+// primitive function
+func envPrimitive(method func(*Ant, ...gp.PrimitiveArgs) gp.PrimitiveArgs) gp.EnvFunc {
+	return func(env any, args ...gp.PrimitiveArgs) (gp.PrimitiveArgs, error) {
+		ant, err := antOf(env)
+		if err != nil {
+			return nil, err
+		}
+		return method(ant, args...), nil
+	}
+}
+
+// envTerminal adapts an action of the ant evaluating the program to a
+// terminal value
+func envTerminal(method func(*Ant, ...gp.PrimitiveArgs) gp.PrimitiveArgs) gp.EnvTerminalFunc {
+	return func(env any) (gp.PrimitiveArgs, error) {
+		ant, err := antOf(env)
+		if err != nil {
+			return nil, err
+		}
+		return FPP(func(args ...gp.PrimitiveArgs) gp.PrimitiveArgs {
+			return method(ant, args...)
+		}), nil
+	}
+}
+
+// NewPrimitiveSet creates the primitives of the ant, they act on the ant the
+// program is evaluated for so the set can be shared by concurrent evaluations
+func NewPrimitiveSet() *gp.PrimitiveSet {
+	ps := gp.NewPrimitiveSet([]gp.Type{}, reflect.Func)
+	ps.AddPrimitive(gp.NewPrimitive("prog3", ProgN, []gp.Type{reflect.Func, reflect.Func, reflect.Func}, reflect.Func))
+	ps.AddPrimitive(gp.NewPrimitive("prog2", ProgN, []gp.Type{reflect.Func, reflect.Func}, reflect.Func))
+	ps.AddPrimitive(gp.NewEnvPrimitive("if_food_ahead", envPrimitive((*Ant).IfFoodAhead), []gp.Type{reflect.Func, reflect.Func}, reflect.Func))
+	ps.AddTerminal(gp.NewEnvTerminal("move_forward", reflect.Func, envTerminal((*Ant).MoveForward)))
+	ps.AddTerminal(gp.NewEnvTerminal("turn_left", reflect.Func, envTerminal((*Ant).TurnLeft)))
+	ps.AddTerminal(gp.NewEnvTerminal("turn_right", reflect.Func, envTerminal((*Ant).TurnRight)))
+	return ps
+}
+
+// eval runs the program until the ant used up its moves, every run of the
+// program is a step of the guard so a program not spending moves is stopped
+func eval(ant *Ant, ind gp.Individual, guard *gp.EvalGuard) error {
 	ant.Reset()
-	routine := ind.Tree().Compile().(func(...gp.PrimitiveArgs) gp.PrimitiveArgs)
+	res, err := ind.Tree().CompileEnv(guard, ant)
+	if err != nil {
+		return err
+	}
+	routine := res.(func(...gp.PrimitiveArgs) gp.PrimitiveArgs)
 	for ant.moves < ant.maxMoves {
+		if err := guard.Step("routine"); err != nil {
+			return err
+		}
 		routine()
 	}
 	ind.Fitness().SetValues([]float32{float32(ant.eaten)})
+	return nil
 }
 
-func Main() {
-	/*
-	  Best in gen: [84.00]
-	  best algo:
-	  prog3(prog3(turn_right, move_forward, turn_left), prog3(if_food_ahead(move_forward, if_food_ahead(prog3(turn_left, if_food_ahead(move_forward, move_forward), turn_right), turn_left)), if_food_ahead(turn_left, move_forward), if_food_ahead(if_food_ahead(prog2(move_forward, move_forward), turn_left), turn_right)), move_forward)
-	*/
-	matrix, err := ParseMatrix("examples/ant/matrix.txt")
-	if err != nil {
-		panic(err)
-	}
-
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	ant := NewAnt(600, matrix)
-
+// Run evolves a population for ants with maxMoves on the matrix, every
+// evaluation gets its own ant. It also returns the number of evaluations that
+// failed, those programs got a fitness of 0. All randomness is drawn from r,
+// so the same seed yields the same final population.
+func Run(matrix Matrix, maxMoves int, r *rand.Rand, popSize, numGen int) ([]gp.Individual, int) {
 	inds := []gp.Individual{}
 
-	ps := gp.NewPrimitiveSet([]reflect.Kind{}, reflect.Func)
-	ps.AddPrimitive(gp.NewPrimitive("prog3", ProgN, []reflect.Kind{reflect.Func, reflect.Func, reflect.Func}, reflect.Func))
-	ps.AddPrimitive(gp.NewPrimitive("prog2", ProgN, []reflect.Kind{reflect.Func, reflect.Func}, reflect.Func))
-	ps.AddPrimitive(gp.NewPrimitive("if_food_ahead", ant.IfFoodAhead, []reflect.Kind{reflect.Func, reflect.Func}, reflect.Func))
-	ps.AddTerminal(gp.NewTerminal("move_forward", reflect.Func, ant.MoveForward))
-	ps.AddTerminal(gp.NewTerminal("turn_left", reflect.Func, ant.TurnLeft))
-	ps.AddTerminal(gp.NewTerminal("turn_right", reflect.Func, ant.TurnRight))
+	ps := NewPrimitiveSet()
+	if _, err := ps.Validate(); err != nil {
+		panic(err)
+	}
 
-	for i := 0; i < 300; i++ {
+	for i := 0; i < popSize; i++ {
 		fit, err := gp.NewFitness([]float32{1})
 		if err != nil {
 			panic(err)
@@ -317,21 +370,47 @@ func Main() {
 	}
 
 	settings := gp.AlgorithmSettings{
-		NumGen:               40,
+		NumGen:               numGen,
 		MutationProbability:  0.5,
 		CrossoverProbability: 0.2,
 		SelectionSize:        len(inds),
 		TournamentSize:       7,
-		CrossOverFunc:        gp.CXOnePoint,
-		MutatorFunc: gp.NewUniformMutator(ps, func(ps *gp.PrimitiveSet, type_ reflect.Kind) []gp.Node {
+		CrossoverBias:        90,
+		CrossOverFunc:        gp.CXOnePointLeafBiased,
+		MutatorFunc: gp.NewUniformMutator(ps, func(ps *gp.PrimitiveSet, type_ gp.Type) []gp.Node {
 			return gp.GenerateTree(ps, 0, 2, gp.GenFull, type_, r).Nodes()
 		}, r).Mutate,
 	}
-	inds = gp.EaSimple(inds, ps, func(ind gp.Individual) {
-		eval(ant, ind)
-	}, settings, r)
+	failures := 0
+	evalFunction := gp.GuardedEval(func(ind gp.Individual, guard *gp.EvalGuard) error {
+		return eval(NewAnt(maxMoves, matrix), ind, guard)
+	}, evalBudget, 0, func(_ error) []float32 {
+		failures++
+		return []float32{0}
+	})
+	inds, _ = gp.EaSimple(inds, ps, evalFunction, settings, r)
+	return inds, failures
+}
+
+func Main() {
+	/*
+	  Best in gen: [84.00]
+	  best algo:
+	  prog3(prog3(turn_right, move_forward, turn_left), prog3(if_food_ahead(move_forward, if_food_ahead(prog3(turn_left, if_food_ahead(move_forward, move_forward), turn_right), turn_left)), if_food_ahead(turn_left, move_forward), if_food_ahead(if_food_ahead(prog2(move_forward, move_forward), turn_left), turn_right)), move_forward)
+	*/
+	matrix, err := ParseMatrix("examples/ant/matrix.txt")
+	if err != nil {
+		panic(err)
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	inds, failures := Run(matrix, 600, r, 300, 40)
 	best := slices.MaxFunc(inds, gp.FitnessMaxFunc)
-	eval(ant, best)
+	ant := NewAnt(600, matrix)
+	if err := eval(ant, best, gp.NewEvalGuard(context.Background(), evalBudget)); err != nil {
+		panic(err)
+	}
+	fmt.Printf("invalid programs: %d\n", failures)
 	fmt.Printf("best algo: \n%s\n", best.Tree().String())
 	fmt.Printf("best matrix: \n%s\n", ant.matrix.String())
 
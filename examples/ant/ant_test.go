@@ -0,0 +1,75 @@
+package ant
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+
+	"main/gp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunReproducible(t *testing.T) {
+	matrix, err := ParseMatrix("matrix.txt")
+	assert.NoError(t, err)
+	run := func() []gp.Individual {
+		inds, _ := Run(matrix, 600, rand.New(rand.NewSource(42)), 50, 5)
+		return inds
+	}
+	first, second := run(), run()
+	assert.Len(t, second, len(first))
+	for i := range first {
+		assert.Equal(t, first[i].Tree().String(), second[i].Tree().String())
+		assert.Equal(t, first[i].Fitness().GetValues(), second[i].Fitness().GetValues())
+	}
+}
+
+func TestEvalStopsIdleProgram(t *testing.T) {
+	matrix, err := ParseMatrix("matrix.txt")
+	assert.NoError(t, err)
+	var wait FPP = func(_ ...gp.PrimitiveArgs) gp.PrimitiveArgs { return nil }
+	ind := &AntIndividual{
+		tree:    gp.NewPrimitiveTree([]gp.Node{gp.NewTerminal("wait", reflect.Func, wait)}),
+		fitness: &gp.Fitness{},
+	}
+	err = eval(NewAnt(600, matrix), ind, gp.NewEvalGuard(context.Background(), 100))
+	var evalErr *gp.EvalError
+	assert.ErrorAs(t, err, &evalErr)
+	assert.Equal(t, gp.FailureBudget, evalErr.Failure)
+}
+
+func TestEvalConcurrent(t *testing.T) {
+	matrix, err := ParseMatrix("matrix.txt")
+	assert.NoError(t, err)
+	inds, _ := Run(matrix, 600, rand.New(rand.NewSource(42)), 30, 2)
+	copies := make([]gp.Individual, len(inds))
+	var wg sync.WaitGroup
+	for i, ind := range inds {
+		copies[i] = ind.Copy()
+		copies[i].Fitness().SetValues([]float32{-1})
+		wg.Add(1)
+		go func(ind gp.Individual) {
+			defer wg.Done()
+			assert.NoError(t, eval(NewAnt(600, matrix), ind, gp.NewEvalGuard(context.Background(), evalBudget)))
+		}(copies[i])
+	}
+	wg.Wait()
+	for i, ind := range inds {
+		assert.NoError(t, eval(NewAnt(600, matrix), ind, gp.NewEvalGuard(context.Background(), evalBudget)))
+		assert.Equal(t, ind.Fitness().GetValues(), copies[i].Fitness().GetValues())
+	}
+}
+
+func TestCompileWithoutAnt(t *testing.T) {
+	tree, err := gp.ParseTree("if_food_ahead(move_forward, turn_left)", NewPrimitiveSet())
+	assert.NoError(t, err)
+	_, err = tree.Compile()
+	assert.Error(t, err)
+	_, err = tree.CompileFunc()()
+	assert.Error(t, err)
+	_, err = tree.CompileEnv(gp.NewEvalGuard(context.Background(), 0), "not an ant")
+	assert.EqualError(t, err, "eval error at move_forward: the environment has to be an *Ant, got string")
+}
@@ -7,11 +7,32 @@ import (
 	"golang.org/x/exp/slices"
 )
 
+// VariationStats counts the variations VarAnd attempted and the ones that
+// left the individuals unchanged, grouped by reason
+type VariationStats struct {
+	Crossovers     int
+	CrossoverNoOps int
+	Mutations      int
+	MutationNoOps  int
+	NoOpReasons    map[NoChangeReason]int
+}
+
+func (vs VariationStats) String() string {
+	return fmt.Sprintf("crossovers: %d (%d no-op), mutations: %d (%d no-op)", vs.Crossovers, vs.CrossoverNoOps, vs.Mutations, vs.MutationNoOps)
+}
+
 // TODO make mutator and CX function a parameter
-func VarAnd(offs []Individual, ps *PrimitiveSet, cxFunc CrossOver, mutFunc Mutator, cxpb, mutpb float32, r *rand.Rand) {
+func VarAnd(offs []Individual, ps *PrimitiveSet, cxFunc CrossOver, mutFunc Mutator, cxpb, mutpb float32, cxBias int, r *rand.Rand) VariationStats {
+	stats := VariationStats{NoOpReasons: map[NoChangeReason]int{}}
 	for i := 1; i < len(offs); i += 2 {
-		if rand.Float32() < cxpb {
-			tree1, tree2 := cxFunc(*offs[i-1].Tree(), *offs[i].Tree(), r, 0)
+		if r.Float32() < cxpb {
+			stats.Crossovers++
+			tree1, tree2, res := cxFunc(*offs[i-1].Tree(), *offs[i].Tree(), r, cxBias)
+			if !res.Changed {
+				stats.CrossoverNoOps++
+				stats.NoOpReasons[res.Reason]++
+				continue
+			}
 			offs[i-1].Tree().ReplaceNodes(tree1.Nodes())
 			offs[i].Tree().ReplaceNodes(tree2.Nodes())
 			offs[i-1].Fitness().DelValues()
@@ -19,13 +40,19 @@ func VarAnd(offs []Individual, ps *PrimitiveSet, cxFunc CrossOver, mutFunc Mutat
 		}
 	}
 	for i := 0; i < len(offs); i++ {
-		if rand.Float32() < mutpb {
-			offs[i].Tree().ReplaceNodes(
-				mutFunc(offs[i].Tree()).Nodes(),
-			)
+		if r.Float32() < mutpb {
+			stats.Mutations++
+			tree, res := mutFunc(offs[i].Tree())
+			if !res.Changed {
+				stats.MutationNoOps++
+				stats.NoOpReasons[res.Reason]++
+				continue
+			}
+			offs[i].Tree().ReplaceNodes(tree.Nodes())
 			offs[i].Fitness().DelValues()
 		}
 	}
+	return stats
 }
 
 type AlgorithmSettings struct {
@@ -34,26 +61,47 @@ type AlgorithmSettings struct {
 	SelectionSize        int
 	MutationProbability  float32
 	CrossoverProbability float32
+	CrossoverBias        int // passed to CrossOverFunc, see CXOnePointLeafBiased
 	CrossOverFunc        CrossOver
 	MutatorFunc          Mutator
+	DynamicLimiter       *DynamicLimiter // optional, applied after the variation
 }
 
-func EaSimple(inds []Individual, ps *PrimitiveSet, evalFunction func(Individual), setting AlgorithmSettings, r *rand.Rand) []Individual {
+// EaSimple runs the evolution and returns the final population with the
+// variation statistics of every generation
+func EaSimple(inds []Individual, ps *PrimitiveSet, evalFunction func(Individual), setting AlgorithmSettings, r *rand.Rand) ([]Individual, []VariationStats) {
+	var allStats []VariationStats
+	if setting.DynamicLimiter != nil {
+		setting.DynamicLimiter.Observe(inds)
+	}
 	for gen := 0; gen < setting.NumGen; gen++ {
 		fmt.Printf("------------------------------------------------------------------- (%d) %d\n", gen+1, len(inds))
 		offsprings := SelTournament(inds, setting.SelectionSize, setting.TournamentSize, r)
+		var parents []Individual
+		if setting.DynamicLimiter != nil {
+			for _, o := range offsprings {
+				parents = append(parents, o.Copy())
+			}
+		}
 
 		// TODO pass on settings?
-		VarAnd(offsprings, ps, setting.CrossOverFunc, setting.MutatorFunc, setting.CrossoverProbability, setting.MutationProbability, r)
+		stats := VarAnd(offsprings, ps, setting.CrossOverFunc, setting.MutatorFunc, setting.CrossoverProbability, setting.MutationProbability, setting.CrossoverBias, r)
+		allStats = append(allStats, stats)
+		if setting.DynamicLimiter != nil {
+			setting.DynamicLimiter.Filter(parents, offsprings, evalFunction)
+		}
 
 		for i := range offsprings {
 			if !offsprings[i].Fitness().Valid() {
 				evalFunction(offsprings[i])
 			}
 		}
+		if setting.DynamicLimiter != nil {
+			setting.DynamicLimiter.Observe(offsprings)
+		}
 		best := slices.MaxFunc(offsprings, FitnessMaxFunc)
-		fmt.Printf("Best in gen: %s\n", best.Fitness().String())
+		fmt.Printf("Best in gen: %s, %s\n", best.Fitness().String(), stats)
 		inds = offsprings
 	}
-	return inds
+	return inds, allStats
 }
@@ -3,7 +3,6 @@ package gp
 import (
 	"github.com/stretchr/testify/assert"
 	"math/rand"
-	"reflect"
 	"testing"
 )
 
@@ -21,13 +20,13 @@ func generateInds(amount int, initialFitness, initialWeight float32, ps *Primiti
 }
 
 func getMutator(ps *PrimitiveSet, r *rand.Rand) Mutator {
-	return StaticMutatorLimiter(NewUniformMutator(ps, func(ps *PrimitiveSet, type_ reflect.Kind) []Node {
+	return StaticMutatorLimiter(NewUniformMutator(ps, func(ps *PrimitiveSet, type_ Type) []Node {
 		return GenerateTree(ps, 0, 2, GenGrow, type_, r).Nodes()
-	}, r).Mutate, 17)
+	}, r).Mutate, MeasureSize, 17)
 }
 
 func getCrossOver() CrossOver {
-	return StaticCrossOverLimiter(CXOnePoint, 17)
+	return StaticCrossOverLimiter(CXOnePoint, MeasureSize, 17)
 }
 
 func TestVarAndNoChange(t *testing.T) {
@@ -35,7 +34,7 @@ func TestVarAndNoChange(t *testing.T) {
 	ps := getPrimitiveSet()
 
 	inds := generateInds(10, 1, 2, ps, r)
-	VarAnd(inds, ps, getCrossOver(), getMutator(ps, r), 0, 0, r)
+	VarAnd(inds, ps, getCrossOver(), getMutator(ps, r), 0, 0, 0, r)
 
 	for i := range inds {
 		assert.True(t, inds[i].Fitness().Valid())
@@ -47,7 +46,7 @@ func TestVarAndMutationOnly(t *testing.T) {
 	ps := getPrimitiveSet()
 
 	inds := generateInds(10, 1, 2, ps, r)
-	VarAnd(inds, ps, getCrossOver(), getMutator(ps, r), 0, 1, r)
+	VarAnd(inds, ps, getCrossOver(), getMutator(ps, r), 0, 1, 0, r)
 
 	for i := range inds {
 		assert.False(t, inds[i].Fitness().Valid())
@@ -67,7 +66,7 @@ func TestVarAndCXOnly(t *testing.T) {
 	ps := getPrimitiveSet()
 
 	inds := generateInds(10, 1, 2, ps, r)
-	VarAnd(inds, ps, getCrossOver(), getMutator(ps, r), 1, 0, r)
+	VarAnd(inds, ps, getCrossOver(), getMutator(ps, r), 1, 0, 0, r)
 
 	for i := range inds {
 		assert.False(t, inds[i].Fitness().Valid())
@@ -87,7 +86,7 @@ func TestVarAndCXAndMut(t *testing.T) {
 	ps := getPrimitiveSet()
 
 	inds := generateInds(10, 1, 2, ps, r)
-	VarAnd(inds, ps, getCrossOver(), getMutator(ps, r), 1, 1, r)
+	VarAnd(inds, ps, getCrossOver(), getMutator(ps, r), 1, 1, 0, r)
 
 	for i := range inds {
 		assert.False(t, inds[i].Fitness().Valid())
@@ -102,6 +101,39 @@ func TestVarAndCXAndMut(t *testing.T) {
 	}
 }
 
+func TestVarAndStats(t *testing.T) {
+	r := rand.New(rand.NewSource(20))
+	ps := getPrimitiveSet()
+
+	inds := generateInds(10, 1, 2, ps, r)
+	noop := func(ind *PrimitiveTree) (*PrimitiveTree, VariationResult) {
+		return ind, unchanged(ReasonNoCandidate)
+	}
+	stats := VarAnd(inds, ps, getCrossOver(), noop, 1, 1, 0, r)
+
+	assert.Equal(t, 5, stats.Crossovers)
+	assert.Equal(t, 0, stats.CrossoverNoOps)
+	assert.Equal(t, 10, stats.Mutations)
+	assert.Equal(t, 10, stats.MutationNoOps)
+	assert.Equal(t, map[NoChangeReason]int{ReasonNoCandidate: 10}, stats.NoOpReasons)
+}
+
+func TestVarAndNoOpKeepsFitness(t *testing.T) {
+	r := rand.New(rand.NewSource(20))
+	ps := getPrimitiveSet()
+
+	inds := generateInds(10, 1, 2, ps, r)
+	noop := func(ind1, ind2 PrimitiveTree, _ *rand.Rand, _ int) (PrimitiveTree, PrimitiveTree, VariationResult) {
+		return ind1, ind2, unchanged(ReasonNoCommonTypes)
+	}
+	stats := VarAnd(inds, ps, noop, getMutator(ps, r), 1, 0, 0, r)
+
+	assert.Equal(t, 5, stats.CrossoverNoOps)
+	for i := range inds {
+		assert.True(t, inds[i].Fitness().Valid())
+	}
+}
+
 func TestEaSimple(t *testing.T) {
 	r := rand.New(rand.NewSource(20))
 	ps := getPrimitiveSet()
@@ -120,7 +152,8 @@ func TestEaSimple(t *testing.T) {
 		CrossOverFunc:        getCrossOver(),
 		MutatorFunc:          getMutator(ps, r),
 	}
-	inds = EaSimple(inds, ps, evalFunc, setting, r)
+	inds, stats := EaSimple(inds, ps, evalFunc, setting, r)
+	assert.Len(t, stats, setting.NumGen)
 
 	for i := range inds {
 		assert.True(t, inds[i].Fitness().Valid())
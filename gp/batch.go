@@ -0,0 +1,72 @@
+package gp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EvaluateBatch evaluates the tree for every row of the given columns, one
+// column per argument terminal. Primitives created with NewVectorPrimitive
+// get whole columns, everything else is evaluated row by row.
+func (pt *PrimitiveTree) EvaluateBatch(columns ...[]PrimitiveArgs) ([]PrimitiveArgs, error) {
+	rows := -1
+	for i, c := range columns {
+		if rows >= 0 && len(c) != rows {
+			return nil, errors.New(fmt.Sprintf("column %d has %d rows, expected %d", i, len(c), rows))
+		}
+		rows = len(c)
+	}
+	if rows < 0 {
+		rows = 1
+	}
+	res, _, err := pt.evaluateColumn(0, columns, rows)
+	return res, err
+}
+
+func (pt *PrimitiveTree) evaluateColumn(index int, columns [][]PrimitiveArgs, rows int) ([]PrimitiveArgs, int, error) {
+	if index >= len(pt.stack) {
+		return nil, 0, &EvalError{Failure: FailureInvalid, Err: errors.New("incomplete tree")}
+	}
+	node := pt.stack[index]
+	if term, ok := node.(*Terminal); ok && term.argument {
+		var argIndex int
+		fmt.Sscanf(term.name, "__ARG__%d", &argIndex)
+		if argIndex >= len(columns) {
+			return nil, 0, &EvalError{Failure: FailureEval, Node: term.name, Err: errors.New("no column for argument terminal")}
+		}
+		return columns[argIndex], index + 1, nil
+	}
+
+	children := make([][]PrimitiveArgs, node.Arity())
+	next := index + 1
+	for i := range children {
+		var err error
+		children[i], next, err = pt.evaluateColumn(next, columns, rows)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if prim, ok := node.(*Primitive); ok && prim.vectorFunc != nil {
+		res := prim.vectorFunc(children...)
+		if len(res) != rows {
+			return nil, 0, &EvalError{Failure: FailureEval, Node: node.Name(), Err: errors.New(fmt.Sprintf("vector function returned %d rows, expected %d", len(res), rows))}
+		}
+		return res, next, nil
+	}
+
+	res := make([]PrimitiveArgs, rows)
+	buf := make([]PrimitiveArgs, rows*len(children))
+	for row := 0; row < rows; row++ {
+		args := buf[row*len(children) : (row+1)*len(children)]
+		for i := range children {
+			args[i] = children[i][row]
+		}
+		value, err := node.Eval(args)
+		if err != nil {
+			return nil, 0, &EvalError{Failure: FailureEval, Node: node.Name(), Err: errors.New(fmt.Sprintf("row %d: %s", row, err.Error()))}
+		}
+		res[row] = value
+	}
+	return res, next, nil
+}
@@ -0,0 +1,70 @@
+package gp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateBatch(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{reflect.Int, reflect.String}, reflect.Int)
+	ps.AddPrimitive(prim1)
+	ps.AddPrimitive(prim2)
+	tree, err := ParseTree("prim1(__ARG__0, prim2(__ARG__1, __ARG__0))", ps)
+	assert.NoError(t, err)
+
+	ints := []PrimitiveArgs{1, 2, 3}
+	strs := []PrimitiveArgs{"a", "bb", ""}
+	res, err := tree.EvaluateBatch(ints, strs)
+	assert.NoError(t, err)
+	assert.Equal(t, []PrimitiveArgs{1, 8, 0}, res)
+	for i := range ints {
+		assert.Equal(t, mustCompile(t, tree, ints[i], strs[i]), res[i])
+	}
+
+	_, err = tree.EvaluateBatch(ints, strs[:2])
+	assert.EqualError(t, err, "column 1 has 2 rows, expected 3")
+	_, err = tree.EvaluateBatch(ints)
+	assert.EqualError(t, err, "eval error at __ARG__1: no column for argument terminal")
+	_, err = tree.EvaluateBatch(strs, ints)
+	var evalErr *EvalError
+	assert.ErrorAs(t, err, &evalErr)
+	assert.Equal(t, FailureEval, evalErr.Failure)
+	assert.Equal(t, "prim2", evalErr.Node)
+}
+
+func TestEvaluateBatchIncomplete(t *testing.T) {
+	for _, nodes := range [][]Node{nil, {prim1, term1}} {
+		_, err := NewPrimitiveTree(nodes).EvaluateBatch()
+		assert.Equal(t, &EvalError{Failure: FailureInvalid, Err: errors.New("incomplete tree")}, err)
+	}
+}
+
+func TestEvaluateBatchVectorPrimitive(t *testing.T) {
+	calls := 0
+	add := NewVectorPrimitive("add", func(a ...PrimitiveArgs) PrimitiveArgs {
+		return a[0].(int) + a[1].(int)
+	}, func(cols ...[]PrimitiveArgs) []PrimitiveArgs {
+		calls++
+		res := make([]PrimitiveArgs, len(cols[0]))
+		for i := range res {
+			res[i] = cols[0][i].(int) + cols[1][i].(int)
+		}
+		return res
+	}, []Type{reflect.Int, reflect.Int}, reflect.Int)
+
+	ps := NewPrimitiveSet([]Type{reflect.Int, reflect.String}, reflect.Int)
+	ps.AddPrimitive(add)
+	ps.AddPrimitive(prim1)
+	ps.AddTerminal(term1)
+	tree, err := ParseTree(`add(add(__ARG__0, 4), prim1(__ARG__0, __ARG__1))`, ps)
+	assert.NoError(t, err)
+
+	res, err := tree.EvaluateBatch([]PrimitiveArgs{1, 2, 3}, []PrimitiveArgs{"a", "bb", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []PrimitiveArgs{6, 10, 7}, res)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 6, mustCompile(t, tree, 1, "a"))
+}
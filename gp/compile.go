@@ -0,0 +1,76 @@
+package gp
+
+import (
+	"errors"
+	"fmt"
+)
+
+type CompiledTree func(...interface{}) (interface{}, error)
+
+type compiledNode func(args []interface{}, buf []PrimitiveArgs) (interface{}, error)
+
+// CompileFunc walks the tree once and returns a closure with the argument
+// terminals already resolved. Every call allocates a single buffer that
+// holds the arguments of all primitives, nothing is allocated per node.
+// Failures are returned as an EvalError but the closure is not guarded:
+// there is no node budget or deadline and panics of the nodes are not
+// recovered, evaluate untrusted programs with CompileGuarded.
+func (pt *PrimitiveTree) CompileFunc() CompiledTree {
+	bufSize := 0
+	root, _ := pt.compileNode(0, &bufSize)
+	return func(args ...interface{}) (interface{}, error) {
+		return root(args, make([]PrimitiveArgs, bufSize))
+	}
+}
+
+func (pt *PrimitiveTree) compileNode(index int, bufSize *int) (compiledNode, int) {
+	if index >= len(pt.stack) {
+		// the tree is missing arguments, like Compile it fails when called
+		return func(_ []interface{}, _ []PrimitiveArgs) (interface{}, error) {
+			return nil, &EvalError{Failure: FailureInvalid, Err: errors.New("incomplete tree")}
+		}, index
+	}
+	node := pt.stack[index]
+	if term, ok := node.(*Terminal); ok && term.argument {
+		var argIndex int
+		fmt.Sscanf(term.name, "__ARG__%d", &argIndex)
+		return func(args []interface{}, _ []PrimitiveArgs) (interface{}, error) {
+			if argIndex >= len(args) {
+				return nil, &EvalError{Failure: FailureEval, Node: term.name, Err: errors.New("no value for argument terminal")}
+			}
+			return args[argIndex], nil
+		}, index + 1
+	}
+	if node.Arity() == 0 {
+		return func(_ []interface{}, _ []PrimitiveArgs) (interface{}, error) {
+			res, err := node.Eval(nil)
+			if err != nil {
+				return nil, &EvalError{Failure: FailureEval, Node: node.Name(), Err: err}
+			}
+			return res, nil
+		}, index + 1
+	}
+
+	offset := *bufSize
+	*bufSize += node.Arity()
+	children := make([]compiledNode, node.Arity())
+	next := index + 1
+	for i := range children {
+		children[i], next = pt.compileNode(next, bufSize)
+	}
+	return func(args []interface{}, buf []PrimitiveArgs) (interface{}, error) {
+		nodeArgs := buf[offset : offset+len(children)]
+		for i, child := range children {
+			res, err := child(args, buf)
+			if err != nil {
+				return nil, err
+			}
+			nodeArgs[i] = res
+		}
+		res, err := node.Eval(nodeArgs)
+		if err != nil {
+			return nil, &EvalError{Failure: FailureEval, Node: node.Name(), Err: err}
+		}
+		return res, nil
+	}, next
+}
@@ -0,0 +1,75 @@
+package gp
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileFunc(t *testing.T) {
+	tree := NewPrimitiveTree(getValidNodes())
+	res, err := tree.CompileFunc()()
+	assert.NoError(t, err)
+	assert.Equal(t, 5*4*4, res)
+}
+
+func TestCompileFuncMatchesCompile(t *testing.T) {
+	r := rand.New(rand.NewSource(1001))
+	ps := NewPrimitiveSet([]Type{reflect.Int, reflect.String, reflect.Int}, reflect.Int)
+	ps.AddPrimitive(prim1)
+	ps.AddPrimitive(prim2)
+	ps.AddTerminal(term1)
+
+	for i := 0; i < 20; i++ {
+		tree := GenerateTree(ps, 1, 4, GenGrow, ps.RetType, r)
+		f := tree.CompileFunc()
+		for _, args := range [][]interface{}{{1, "a", 2}, {3, "hello", 0}, {2, "", 5}} {
+			res, err := f(args...)
+			assert.NoError(t, err)
+			assert.Equal(t, mustCompile(t, tree, args...), res)
+		}
+	}
+}
+
+func TestCompileFuncErrors(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{reflect.Int, reflect.String}, reflect.Int)
+	ps.AddPrimitive(prim1)
+	tree, _ := ParseTree("prim1(__ARG__0, __ARG__1)", ps)
+	f := tree.CompileFunc()
+
+	_, err := f(4)
+	assert.EqualError(t, err, "eval error at __ARG__1: no value for argument terminal")
+	_, err = f(true, "aloha")
+	assert.Error(t, err)
+	res, err := f(1, "aloha", 12)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, res)
+}
+
+func TestCompileFuncIncomplete(t *testing.T) {
+	for _, nodes := range [][]Node{{}, {prim1, term1}} {
+		_, err := NewPrimitiveTree(nodes).CompileFunc()()
+		assert.Equal(t, &EvalError{Failure: FailureInvalid, Err: errors.New("incomplete tree")}, err)
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	ps := getPrimitiveSet()
+	tree := GenerateTree(ps, 4, 5, GenFull, ps.RetType, r)
+	for i := 0; i < b.N; i++ {
+		tree.Compile()
+	}
+}
+
+func BenchmarkCompileFunc(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	ps := getPrimitiveSet()
+	f := GenerateTree(ps, 4, 5, GenFull, ps.RetType, r).CompileFunc()
+	for i := 0; i < b.N; i++ {
+		f()
+	}
+}
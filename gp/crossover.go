@@ -0,0 +1,119 @@
+package gp
+
+import (
+	"math/rand"
+)
+
+// CXOnePointLeafBiased is a one point crossover where bias is the percentage
+// chance of choosing an internal node as crossover point in each parent,
+// otherwise a leaf is chosen. Koza's 90/10 rule corresponds to a bias of 90.
+func CXOnePointLeafBiased(ind1 PrimitiveTree, ind2 PrimitiveTree, r *rand.Rand, bias int) (PrimitiveTree, PrimitiveTree, VariationResult) {
+	if len(ind1.stack) < 2 || len(ind2.stack) < 2 {
+		return ind1, ind2, unchanged(ReasonTooSmall)
+	}
+	internal := func(n Node) bool { return n.Arity() > 0 }
+	leaf := func(n Node) bool { return n.Arity() == 0 }
+	accept1, accept2 := leaf, leaf
+	if r.Intn(100) < bias {
+		accept1 = internal
+	}
+	if r.Intn(100) < bias {
+		accept2 = internal
+	}
+	return crossSubtrees(ind1, ind2, r, accept1, accept2)
+}
+
+// CXSizeFair swaps a random subtree of the first parent with a subtree of the
+// same type from the second parent whose size is at most 1+2*size of the first
+// one, so the second subtree can not be arbitrarily large (Langdon's size fair crossover)
+func CXSizeFair(ind1 PrimitiveTree, ind2 PrimitiveTree, r *rand.Rand, _ int) (PrimitiveTree, PrimitiveTree, VariationResult) {
+	if len(ind1.stack) < 2 || len(ind2.stack) < 2 {
+		return ind1, ind2, unchanged(ReasonTooSmall)
+	}
+	sizes2 := make([]int, len(ind2.stack))
+	for i := range ind2.stack {
+		begin, end := ind2.SearchSubtree(i)
+		sizes2[i] = end - begin
+	}
+	candidates := func(index1 int) []int {
+		begin, end := ind1.SearchSubtree(index1)
+		var res []int
+		for i, n := range ind2.stack[1:] {
+			if n.Ret() == ind1.stack[index1].Ret() && sizes2[i+1] <= 1+2*(end-begin) {
+				res = append(res, i+1)
+			}
+		}
+		return res
+	}
+
+	var points1 []int
+	for i := 1; i < len(ind1.stack); i++ {
+		if len(candidates(i)) > 0 {
+			points1 = append(points1, i)
+		}
+	}
+	if len(points1) == 0 {
+		return ind1, ind2, unchanged(ReasonNoCommonTypes)
+	}
+	index1 := points1[r.Intn(len(points1))]
+	points2 := candidates(index1)
+	index2 := points2[r.Intn(len(points2))]
+
+	slice1Begin, slice1End := ind1.SearchSubtree(index1)
+	slice2Begin, slice2End := ind2.SearchSubtree(index2)
+	child1Stack := ReplaceInRange(ind1.stack, slice1Begin, slice1End, ind2.stack[slice2Begin:slice2End]...)
+	child2Stack := ReplaceInRange(ind2.stack, slice2Begin, slice2End, ind1.stack[slice1Begin:slice1End]...)
+	return *NewPrimitiveTree(child1Stack), *NewPrimitiveTree(child2Stack), changed
+}
+
+// CXUniform is the homologous GP uniform crossover. The parents are walked
+// together from the root, inside their common region (same type and arity)
+// every primitive with identical signature is swapped with 50% chance, at the
+// border of the region the whole subtrees are swapped with 50% chance.
+func CXUniform(ind1 PrimitiveTree, ind2 PrimitiveTree, r *rand.Rand, _ int) (PrimitiveTree, PrimitiveTree, VariationResult) {
+	var child1, child2 []Node
+	swaps := 0
+	uniformWalk(ind1, ind2, 0, 0, r, &child1, &child2, &swaps)
+	if swaps == 0 {
+		return ind1, ind2, unchanged(ReasonNoCandidate)
+	}
+	return *NewPrimitiveTree(child1), *NewPrimitiveTree(child2), changed
+}
+
+func uniformWalk(ind1 PrimitiveTree, ind2 PrimitiveTree, index1, index2 int, r *rand.Rand, child1, child2 *[]Node, swaps *int) (int, int) {
+	node1, node2 := ind1.stack[index1], ind2.stack[index2]
+	_, end1 := ind1.SearchSubtree(index1)
+	_, end2 := ind2.SearchSubtree(index2)
+	if node1.Ret() != node2.Ret() {
+		*child1 = append(*child1, ind1.stack[index1:end1]...)
+		*child2 = append(*child2, ind2.stack[index2:end2]...)
+		return end1, end2
+	}
+	if node1.Arity() == 0 || node1.Arity() != node2.Arity() {
+		if r.Intn(2) == 0 {
+			*swaps++
+			*child1 = append(*child1, ind2.stack[index2:end2]...)
+			*child2 = append(*child2, ind1.stack[index1:end1]...)
+		} else {
+			*child1 = append(*child1, ind1.stack[index1:end1]...)
+			*child2 = append(*child2, ind2.stack[index2:end2]...)
+		}
+		return end1, end2
+	}
+
+	prim1, ok1 := node1.(*Primitive)
+	prim2, ok2 := node2.(*Primitive)
+	if ok1 && ok2 && prim1.Equals(*prim2) && r.Intn(2) == 0 {
+		*swaps++
+		*child1 = append(*child1, node2)
+		*child2 = append(*child2, node1)
+	} else {
+		*child1 = append(*child1, node1)
+		*child2 = append(*child2, node2)
+	}
+	next1, next2 := index1+1, index2+1
+	for i := 0; i < node1.Arity(); i++ {
+		next1, next2 = uniformWalk(ind1, ind2, next1, next2, r, child1, child2, swaps)
+	}
+	return next1, next2
+}
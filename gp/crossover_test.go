@@ -0,0 +1,123 @@
+package gp
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getBiasedParents(t *testing.T) (*PrimitiveTree, *PrimitiveTree) {
+	ps := getFuncPrimitiveSet()
+	// the only internal node apart from the root is prog2 and if_food_ahead
+	tree1, err := ParseTree("prog3(prog2(turn_left, turn_left), turn_left, turn_left)", ps)
+	assert.NoError(t, err)
+	tree2, err := ParseTree("prog2(if_food_ahead(move_forward, move_forward), move_forward)", ps)
+	assert.NoError(t, err)
+	return tree1, tree2
+}
+
+func TestCXOnePointLeafBiased(t *testing.T) {
+	tree1, tree2 := getBiasedParents(t)
+	r := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 20; i++ {
+		child1, child2, _ := CXOnePointLeafBiased(*tree1, *tree2, r, 0)
+		assert.Len(t, child1.Nodes(), len(tree1.Nodes()))
+		assert.Len(t, child2.Nodes(), len(tree2.Nodes()))
+		assert.Equal(t, 1, strings.Count(child1.String(), "move_forward"))
+		assert.Equal(t, 1, strings.Count(child2.String(), "turn_left"))
+	}
+
+	child1, child2, _ := CXOnePointLeafBiased(*tree1, *tree2, r, 100)
+	assert.Equal(t, "prog3(if_food_ahead(move_forward, move_forward), turn_left, turn_left)", child1.String())
+	assert.Equal(t, "prog2(prog2(turn_left, turn_left), move_forward)", child2.String())
+}
+
+func TestCXOnePointLeafBiasedRatio(t *testing.T) {
+	tree1, tree2 := getBiasedParents(t)
+	r := rand.New(rand.NewSource(7))
+	internal := 0
+	for i := 0; i < 1000; i++ {
+		child1, _, _ := CXOnePointLeafBiased(*tree1, *tree2, r, 90)
+		if strings.Contains(child1.String(), "if_food_ahead") {
+			internal++
+		}
+	}
+	assert.InDelta(t, 900, internal, 40)
+}
+
+func TestCXOnePointLeafBiasedNoInternalNodes(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	tree1 := PrimitiveTree{stack: []Node{prim1, term1, term2}}
+	tree2 := PrimitiveTree{stack: getValidNodes()}
+	child1, child2, _ := CXOnePointLeafBiased(tree1, tree2, r, 100)
+	assert.Equal(t, tree1.Nodes(), child1.Nodes())
+	assert.Equal(t, tree2.Nodes(), child2.Nodes())
+}
+
+func TestCXSizeFair(t *testing.T) {
+	ps := getPrimitiveSet()
+	r := rand.New(rand.NewSource(13))
+	for i := 0; i < 50; i++ {
+		tree1 := GenerateTree(ps, 1, 3, GenFull, ps.RetType, r)
+		tree2 := GenerateTree(ps, 4, 5, GenFull, ps.RetType, r)
+		child1, child2, _ := CXSizeFair(*tree1, *tree2, r, 0)
+		assert.NoError(t, checkNodes(child1.Nodes(), ps.RetType))
+		assert.NoError(t, checkNodes(child2.Nodes(), ps.RetType))
+		assert.Equal(t, len(tree1.Nodes())+len(tree2.Nodes()), len(child1.Nodes())+len(child2.Nodes()))
+		// the received subtree is at most 1+2*size of the removed one, so the child
+		// can grow by at most 1+size of the removed subtree, which is smaller than the tree
+		assert.LessOrEqual(t, len(child1.Nodes()), 2*len(tree1.Nodes()))
+	}
+}
+
+func TestCXSizeFairBound(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	// the only int subtree of tree1 apart from the root is a single terminal so
+	// only a subtree of at most 3 nodes can be received
+	tree1 := PrimitiveTree{stack: []Node{prim2, term2, term1}}
+	tree2 := PrimitiveTree{stack: []Node{prim1, prim1, prim1, term1, term2, term2, term2}}
+	for i := 0; i < 20; i++ {
+		child1, _, _ := CXSizeFair(tree1, tree2, r, 0)
+		assert.Contains(t, [][]Node{
+			{prim2, term2, term1},
+			{prim2, term2, prim1, term1, term2},
+		}, child1.Nodes())
+	}
+}
+
+func TestCXUniform(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	tree1, _ := ParseTree("prog2(prog2(turn_left, turn_left), if_food_ahead(turn_left, turn_left))", ps)
+	tree2, _ := ParseTree("if_food_ahead(prog3(move_forward, move_forward, move_forward), prog2(move_forward, move_forward))", ps)
+	r := rand.New(rand.NewSource(2))
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		child1, child2, _ := CXUniform(*tree1, *tree2, r, 0)
+		assert.NoError(t, checkNodes(child1.Nodes(), ps.RetType))
+		assert.NoError(t, checkNodes(child2.Nodes(), ps.RetType))
+		assert.Equal(t, len(tree1.Nodes())+len(tree2.Nodes()), len(child1.Nodes())+len(child2.Nodes()))
+		// everything in the common region is exchanged, nothing is duplicated
+		assert.Equal(t, 4, strings.Count(child1.String()+child2.String(), "turn_left"))
+		assert.Equal(t, 5, strings.Count(child1.String()+child2.String(), "move_forward"))
+		seen[child1.String()] = true
+	}
+	assert.Contains(t, seen, "prog2(prog3(move_forward, move_forward, move_forward), prog2(move_forward, move_forward))")
+	assert.Contains(t, seen, "if_food_ahead(prog2(turn_left, turn_left), if_food_ahead(turn_left, turn_left))")
+	assert.Contains(t, seen, "prog2(prog2(turn_left, turn_left), if_food_ahead(move_forward, turn_left))")
+	assert.NotContains(t, seen, "prog3(prog2(turn_left, turn_left), if_food_ahead(turn_left, turn_left))")
+}
+
+func TestCXUniformTyped(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	tree1 := PrimitiveTree{stack: getValidNodes()}
+	tree2 := PrimitiveTree{stack: []Node{prim1, prim1, term1, term2, term2}}
+	for i := 0; i < 20; i++ {
+		child1, child2, _ := CXUniform(tree1, tree2, r, 0)
+		assert.NoError(t, checkNodes(child1.Nodes(), reflect.Int))
+		assert.NoError(t, checkNodes(child2.Nodes(), reflect.Int))
+	}
+}
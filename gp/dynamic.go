@@ -0,0 +1,73 @@
+package gp
+
+// DynamicLimiter implements Silva's dynamic limits for bloat control. An
+// offspring may exceed the current limit only if it is also the new best of
+// the run, in that case the limit is raised to its measure. Offsprings above
+// maxLimit are always rejected.
+type DynamicLimiter struct {
+	measure  TreeMeasure
+	limit    int
+	maxLimit int
+	best     *Fitness
+}
+
+func NewDynamicLimiter(measure TreeMeasure, initialLimit int, maxLimit int) *DynamicLimiter {
+	return &DynamicLimiter{
+		measure:  measure,
+		limit:    initialLimit,
+		maxLimit: maxLimit,
+	}
+}
+
+func (dl *DynamicLimiter) Limit() int {
+	return dl.limit
+}
+
+func (dl *DynamicLimiter) Best() *Fitness {
+	return dl.best
+}
+
+// Observe updates the best of run fitness from the evaluated individuals
+func (dl *DynamicLimiter) Observe(inds []Individual) {
+	for _, ind := range inds {
+		if ind.Fitness().Valid() && (dl.best == nil || ind.Fitness().GreaterThan(dl.best)) {
+			fit, _ := NewFitness(ind.Fitness().GetWeights())
+			fit.wvalues = append([]float32{}, ind.Fitness().GetWValues()...)
+			dl.best = fit
+		}
+	}
+}
+
+// Accept tells if the individual can enter the population, individuals above
+// the current limit are evaluated to decide and raise the limit when accepted
+func (dl *DynamicLimiter) Accept(ind Individual, evalFunction func(Individual)) bool {
+	m := dl.measure(ind.Tree())
+	if m <= dl.limit {
+		return true
+	}
+	if m > dl.maxLimit {
+		return false
+	}
+	if !ind.Fitness().Valid() {
+		evalFunction(ind)
+	}
+	if dl.best != nil && !ind.Fitness().GreaterThan(dl.best) {
+		return false
+	}
+	dl.limit = m
+	dl.Observe([]Individual{ind})
+	return true
+}
+
+// Filter replaces every rejected offspring with a copy of the parent at the
+// same position, parents are the individuals before variation
+func (dl *DynamicLimiter) Filter(parents []Individual, offsprings []Individual, evalFunction func(Individual)) int {
+	rejected := 0
+	for i := range offsprings {
+		if !dl.Accept(offsprings[i], evalFunction) {
+			offsprings[i] = parents[i].Copy()
+			rejected++
+		}
+	}
+	return rejected
+}
@@ -0,0 +1,94 @@
+package gp
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newInd(t *testing.T, expr string, fitness ...float32) Individual {
+	tree, err := ParseTree(expr, getPrimitiveSet())
+	assert.NoError(t, err)
+	fit, _ := NewFitness([]float32{1})
+	if len(fitness) > 0 {
+		fit.SetValues(fitness)
+	}
+	return &IndividualImpl{tree: tree, fitness: fit}
+}
+
+func TestDynamicLimiterAccept(t *testing.T) {
+	dl := NewDynamicLimiter(MeasureHeight, 2, 3)
+	dl.Observe([]Individual{newInd(t, "4", 5), newInd(t, `prim1(4, "hello")`, 10), newInd(t, "4")})
+	assert.Equal(t, []float32{10}, dl.Best().GetValues())
+
+	evals := 0
+	evalFunc := func(value float32) func(Individual) {
+		return func(ind Individual) {
+			evals++
+			ind.Fitness().SetValues([]float32{value})
+		}
+	}
+	shallow := `prim1(4, prim2("hello", 4))`
+	deep := `prim1(4, prim2("hello", prim1(4, "hello")))`
+	tooDeep := `prim1(4, prim2("hello", prim1(4, prim2("hello", 4))))`
+
+	// within the limit, no evaluation needed
+	assert.True(t, dl.Accept(newInd(t, shallow), evalFunc(0)))
+	assert.Equal(t, 0, evals)
+
+	// deeper and not better than the best of run
+	assert.False(t, dl.Accept(newInd(t, deep), evalFunc(10)))
+	assert.Equal(t, 1, evals)
+	assert.Equal(t, 2, dl.Limit())
+
+	// deeper and better raises the limit
+	assert.True(t, dl.Accept(newInd(t, deep), evalFunc(11)))
+	assert.Equal(t, 3, dl.Limit())
+	assert.Equal(t, []float32{11}, dl.Best().GetValues())
+
+	// never above the max limit
+	assert.False(t, dl.Accept(newInd(t, tooDeep), evalFunc(100)))
+	assert.Equal(t, 3, dl.Limit())
+}
+
+func TestDynamicLimiterFilter(t *testing.T) {
+	dl := NewDynamicLimiter(MeasureSize, 3, 10)
+	dl.Observe([]Individual{newInd(t, "4", 5)})
+	parents := []Individual{newInd(t, "4", 1), newInd(t, "4", 2)}
+	offsprings := []Individual{newInd(t, `prim1(4, "hello")`), newInd(t, `prim1(4, prim2("hello", 4))`)}
+	rejected := dl.Filter(parents, offsprings, func(ind Individual) {
+		ind.Fitness().SetValues([]float32{1})
+	})
+	assert.Equal(t, 1, rejected)
+	assert.Equal(t, `prim1(4, "hello")`, offsprings[0].Tree().String())
+	assert.Equal(t, "4", offsprings[1].Tree().String())
+}
+
+func TestEaSimpleDynamicLimiter(t *testing.T) {
+	r := rand.New(rand.NewSource(20))
+	ps := getPrimitiveSet()
+
+	inds := generateInds(10, 1, 2, ps, r)
+	evalFunc := func(ind Individual) {
+		ind.Fitness().SetValues([]float32{float32(len(ind.Tree().Nodes()))})
+	}
+	dl := NewDynamicLimiter(MeasureHeight, 2, 4)
+	setting := AlgorithmSettings{
+		NumGen:               10,
+		MutationProbability:  0.5,
+		CrossoverProbability: 0.5,
+		TournamentSize:       4,
+		SelectionSize:        len(inds),
+		CrossOverFunc:        CXOnePoint,
+		MutatorFunc:          getMutator(ps, r),
+		DynamicLimiter:       dl,
+	}
+	inds, _ = EaSimple(inds, ps, evalFunc, setting, r)
+
+	assert.LessOrEqual(t, dl.Limit(), 4)
+	for i := range inds {
+		assert.True(t, inds[i].Fitness().Valid())
+		assert.LessOrEqual(t, inds[i].Tree().Height(), dl.Limit())
+	}
+}
@@ -0,0 +1,47 @@
+package gp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EnvFunc is the function of a primitive that needs the environment of the
+// evaluation, e.g. the world a simulated agent acts in. Creating a fresh
+// environment for every evaluation lets the primitive set be shared by
+// concurrent evaluations. It returns an error for an environment it can not
+// work with.
+type EnvFunc func(env any, args ...PrimitiveArgs) (PrimitiveArgs, error)
+
+// EnvTerminalFunc returns the value of a terminal in the environment
+type EnvTerminalFunc func(env any) (PrimitiveArgs, error)
+
+type envNode interface {
+	EvalEnv(env any, args []PrimitiveArgs) (interface{}, error)
+}
+
+var _ envNode = new(Primitive)
+var _ envNode = new(Terminal)
+
+// NewEnvPrimitive creates a primitive receiving the environment passed to
+// CompileEnv. Evaluated without one (e.g. by Compile, CompileFunc or
+// EvaluateBatch) it fails with an error and f is not called.
+func NewEnvPrimitive(name string, f EnvFunc, argTypes []Type, retType Type) *Primitive {
+	p := NewPrimitive(name, nil, argTypes, retType)
+	p.envFunc = f
+	return p
+}
+
+// NewEnvTerminal creates a terminal whose value is computed from the
+// environment passed to CompileEnv, it is printed by its name. Like the
+// primitives of NewEnvPrimitive it fails without an environment.
+func NewEnvTerminal(name string, retType Type, f EnvTerminalFunc) *Terminal {
+	return &Terminal{
+		name:     name,
+		retType:  normalizeType(retType),
+		envValue: f,
+	}
+}
+
+func noEnvError(name string) error {
+	return errors.New(fmt.Sprintf("%s needs an environment, evaluate it with CompileEnv", name))
+}
@@ -0,0 +1,85 @@
+package gp
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type counter struct {
+	count int
+}
+
+func getEnvNodes() []Node {
+	incr := NewEnvPrimitive("incr", func(env any, args ...PrimitiveArgs) (PrimitiveArgs, error) {
+		c := env.(*counter)
+		c.count += args[0].(int)
+		return c.count, nil
+	}, []Type{reflect.Int}, reflect.Int)
+	count := NewEnvTerminal("count", reflect.Int, func(env any) (PrimitiveArgs, error) {
+		return env.(*counter).count + 1, nil
+	})
+	return []Node{incr, count}
+}
+
+func TestCompileEnv(t *testing.T) {
+	tree := NewPrimitiveTree(getEnvNodes())
+	assert.Equal(t, "incr(count)", tree.String())
+
+	c := &counter{count: 2}
+	res, err := tree.CompileEnv(NewEvalGuard(context.Background(), 0), c)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, res)
+	assert.Equal(t, 5, c.count)
+}
+
+func TestCompileEnvConcurrent(t *testing.T) {
+	tree := NewPrimitiveTree(getEnvNodes())
+	envs := make([]*counter, 20)
+	var wg sync.WaitGroup
+	for i := range envs {
+		envs[i] = &counter{count: i}
+		wg.Add(1)
+		go func(c *counter) {
+			defer wg.Done()
+			_, err := tree.CompileEnv(NewEvalGuard(context.Background(), 0), c)
+			assert.NoError(t, err)
+		}(envs[i])
+	}
+	wg.Wait()
+	for i, c := range envs {
+		assert.Equal(t, 2*i+1, c.count)
+	}
+}
+
+func TestParseEnvTerminal(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	for _, n := range getEnvNodes() {
+		switch node := n.(type) {
+		case *Primitive:
+			ps.AddPrimitive(node)
+		case *Terminal:
+			ps.AddTerminal(node)
+		}
+	}
+	tree, err := ParseTree("incr(incr(count))", ps)
+	assert.NoError(t, err)
+	res, err := tree.CompileEnv(NewEvalGuard(context.Background(), 0), &counter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res)
+}
+
+func TestEnvNodesWithoutEnv(t *testing.T) {
+	tree := NewPrimitiveTree(getEnvNodes())
+	_, err := tree.Compile()
+	assert.EqualError(t, err, "eval error at count: count needs an environment, evaluate it with CompileEnv")
+	_, err = tree.CompileFunc()()
+	assert.EqualError(t, err, "eval error at count: count needs an environment, evaluate it with CompileEnv")
+	_, err = tree.EvaluateBatch()
+	assert.Error(t, err)
+	_, err = getEnvNodes()[0].Eval([]PrimitiveArgs{1})
+	assert.EqualError(t, err, "incr needs an environment, evaluate it with CompileEnv")
+}
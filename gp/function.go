@@ -0,0 +1,46 @@
+package gp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// NewPrimitiveFromFunc creates a primitive from a typed Go function, e.g.
+// func(a, b float64) float64. The argument and return types are read from the
+// signature of fn and the arguments are converted to the parameter types on
+// every call, so they can not get out of sync with the declared types.
+// Arguments that can not be converted are rejected by Eval.
+func NewPrimitiveFromFunc(name string, fn any) (*Primitive, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, errors.New(fmt.Sprintf("primitive %s: %T is not a function", name, fn))
+	}
+	t := v.Type()
+	if t.IsVariadic() {
+		return nil, errors.New(fmt.Sprintf("primitive %s: variadic functions are not supported", name))
+	}
+	if t.NumOut() != 1 {
+		return nil, errors.New(fmt.Sprintf("primitive %s: function must return exactly one value, got %d", name, t.NumOut()))
+	}
+	argTypes := make([]Type, t.NumIn())
+	paramTypes := make([]reflect.Type, t.NumIn())
+	for i := range argTypes {
+		argTypes[i] = t.In(i)
+		paramTypes[i] = t.In(i)
+	}
+	f := func(args ...PrimitiveArgs) PrimitiveArgs {
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			if arg == nil {
+				in[i] = reflect.Zero(t.In(i))
+			} else {
+				in[i] = reflect.ValueOf(arg).Convert(t.In(i))
+			}
+		}
+		return v.Call(in)[0].Interface()
+	}
+	p := NewPrimitive(name, f, argTypes, t.Out(0))
+	p.paramTypes = paramTypes
+	return p, nil
+}
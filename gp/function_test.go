@@ -0,0 +1,71 @@
+package gp
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPrimitiveFromFunc(t *testing.T) {
+	repeat, err := NewPrimitiveFromFunc("prim2", strings.Repeat)
+	assert.NoError(t, err)
+	assert.Equal(t, []Type{reflect.String, reflect.Int}, repeat.argTypes)
+	assert.Equal(t, reflect.String, repeat.Ret())
+	assert.True(t, repeat.Equals(*prim2))
+
+	mul, err := NewPrimitiveFromFunc("prim1", func(a int, s string) int { return a * len(s) })
+	assert.NoError(t, err)
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	ps.AddPrimitive(mul)
+	ps.AddPrimitive(repeat)
+	ps.AddTerminal(term1)
+	ps.AddTerminal(term2)
+	tree, err := ParseTree(`prim1(4, prim2("hello", 4))`, ps)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*4*4, mustCompile(t, tree))
+
+	r := rand.New(rand.NewSource(20))
+	for i := 0; i < 10; i++ {
+		mustCompile(t, GenerateTree(ps, 1, 4, GenGrow, ps.RetType, r))
+	}
+
+	// named types are kept apart from their kind
+	type celsius float64
+	half, _ := NewPrimitiveFromFunc("half", func(c celsius) celsius { return c / 2 })
+	assert.Equal(t, TypeFor[celsius](), half.Ret())
+	res, err := half.Eval([]PrimitiveArgs{celsius(3)})
+	assert.NoError(t, err)
+	assert.Equal(t, celsius(1.5), res)
+	_, err = half.Eval([]PrimitiveArgs{3.0})
+	assert.EqualError(t, err, "half invalid type for 1th argument (3) expected gp.celsius got float64")
+
+	// arguments of the declared kind that can not be converted are rejected
+	type point struct{ x, y int }
+	type pair struct{ a, b string }
+	x, _ := NewPrimitiveFromFunc("x", func(p point) int { return p.x })
+	x.argTypes = []Type{reflect.Struct}
+	res, err = x.Eval([]PrimitiveArgs{point{1, 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res)
+	_, err = x.Eval([]PrimitiveArgs{pair{"a", "b"}})
+	assert.EqualError(t, err, "x invalid type for 1th argument ({a b}) expected struct got gp.pair")
+}
+
+func TestNewPrimitiveFromFuncErrors(t *testing.T) {
+	for _, tc := range []struct {
+		fn  any
+		msg string
+	}{
+		{4, "primitive p: int is not a function"},
+		{(func(int) int)(nil), "primitive p: func(int) int is not a function"},
+		{func(a ...int) int { return 0 }, "primitive p: variadic functions are not supported"},
+		{func(a int) {}, "primitive p: function must return exactly one value, got 0"},
+		{func(a int) (int, error) { return a, nil }, "primitive p: function must return exactly one value, got 2"},
+	} {
+		_, err := NewPrimitiveFromFunc("p", tc.fn)
+		assert.EqualError(t, err, tc.msg)
+	}
+}
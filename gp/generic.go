@@ -0,0 +1,42 @@
+package gp
+
+import "math/rand"
+
+// NewPrimitive1 is NewPrimitiveFromFunc for a function with one argument, the
+// signature is checked by the compiler so the only error left is a nil f
+// which panics. There are variants up to three arguments, functions taking
+// more go through NewPrimitiveFromFunc.
+func NewPrimitive1[A, R any](name string, f func(A) R) *Primitive {
+	return mustPrimitiveFromFunc(name, f)
+}
+
+// NewPrimitive2 is NewPrimitive1 for functions with two arguments
+func NewPrimitive2[A, B, R any](name string, f func(A, B) R) *Primitive {
+	return mustPrimitiveFromFunc(name, f)
+}
+
+// NewPrimitive3 is NewPrimitive1 for functions with three arguments
+func NewPrimitive3[A, B, C, R any](name string, f func(A, B, C) R) *Primitive {
+	return mustPrimitiveFromFunc(name, f)
+}
+
+func mustPrimitiveFromFunc(name string, f any) *Primitive {
+	p, err := NewPrimitiveFromFunc(name, f)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// NewTypedTerminal creates a terminal returning value, typed by T
+func NewTypedTerminal[T any](name string, value T) *Terminal {
+	return NewTerminal(name, TypeFor[T](), value)
+}
+
+// NewTypedEphemeral creates an ephemeral constant typed by T, add it to a
+// primitive set with AddEphemeral
+func NewTypedEphemeral[T any](name string, generator func(*rand.Rand) T) *Ephemeral {
+	return NewEphemeral(name, TypeFor[T](), func(r *rand.Rand) PrimitiveArgs {
+		return generator(r)
+	})
+}
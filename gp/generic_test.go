@@ -0,0 +1,54 @@
+package gp
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenericPrimitives(t *testing.T) {
+	mul := NewPrimitive2("prim1", func(a int, s string) int { return a * len(s) })
+	repeat := NewPrimitive2("prim2", strings.Repeat)
+	assert.True(t, mul.Equals(*prim1))
+	assert.True(t, repeat.Equals(*prim2))
+
+	// generic and untyped nodes share the primitive set
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	ps.AddPrimitive(mul)
+	ps.AddPrimitive(prim2)
+	ps.AddTerminal(NewTypedTerminal("term1", 4))
+	ps.AddTerminal(term2)
+	ps.AddEphemeral(NewTypedEphemeral("rand_int", func(r *rand.Rand) int { return r.Intn(10) }))
+	tree, err := ParseTree(`prim1(4, prim2("hello", 7))`, ps)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*7*4, mustCompile(t, tree))
+
+	r := rand.New(rand.NewSource(23))
+	for i := 0; i < 10; i++ {
+		mustCompile(t, GenerateTree(ps, 1, 4, GenGrow, ps.RetType, r))
+	}
+}
+
+func TestGenericPrimitivesConversion(t *testing.T) {
+	type meters float64
+	double := NewPrimitive1("double", func(a float64) float64 { return 2 * a })
+	choose := NewPrimitive3("choose", func(c bool, a, b any) any {
+		if c {
+			return a
+		}
+		return b
+	})
+	assert.Equal(t, []Type{reflect.Bool, reflect.Interface, reflect.Interface}, choose.argTypes)
+
+	res, err := double.Eval([]PrimitiveArgs{meters(1.5)})
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, res)
+	res, err = choose.Eval([]PrimitiveArgs{false, 1, nil})
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+
+	assert.Panics(t, func() { NewPrimitive1[int, int]("nil", nil) })
+}
@@ -1,15 +1,17 @@
 package gp
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
+	"math"
 	"math/rand"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
 )
 
 type PrimitiveArgs any
@@ -22,22 +24,26 @@ type Individual interface {
 
 type PrimitiveFunc func(...PrimitiveArgs) PrimitiveArgs
 
-type GenCondition func(int, int, int, int, *PrimitiveSet) bool
+// VectorFunc receives one column per argument and returns the result column
+type VectorFunc func(...[]PrimitiveArgs) []PrimitiveArgs
 
-var GenGrow GenCondition = func(height int, depth int, min int, max int, ps *PrimitiveSet) bool {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return depth == height || (depth >= min && r.Float32() < ps.TerminalRatio())
+// GenCondition tells if a terminal should be placed at depth, the tree can
+// only grow deeper than height if its kind has no terminals
+type GenCondition func(int, int, int, int, *PrimitiveSet, *rand.Rand) bool
+
+var GenGrow GenCondition = func(height int, depth int, min int, max int, ps *PrimitiveSet, r *rand.Rand) bool {
+	return depth >= height || (depth >= min && r.Float32() < ps.TerminalRatio())
 }
 
-var GenFull GenCondition = func(height int, depth int, _min int, _max int, _ps *PrimitiveSet) bool {
-	return depth == height
+var GenFull GenCondition = func(height int, depth int, _min int, _max int, _ps *PrimitiveSet, _r *rand.Rand) bool {
+	return depth >= height
 }
 
-var GenHalfAndHalf GenCondition = func(height int, depth int, min int, max int, ps *PrimitiveSet) bool {
-	if rand.Intn(2) == 0 {
-		return GenGrow(height, depth, min, max, ps)
+var GenHalfAndHalf GenCondition = func(height int, depth int, min int, max int, ps *PrimitiveSet, r *rand.Rand) bool {
+	if r.Intn(2) == 0 {
+		return GenGrow(height, depth, min, max, ps, r)
 	}
-	return GenFull(height, depth, min, max, ps)
+	return GenFull(height, depth, min, max, ps, r)
 }
 
 // ------- Primitive Tree
@@ -63,37 +69,10 @@ func (pt *PrimitiveTree) String() string {
 	return "."
 }
 
-func (pt *PrimitiveTree) Compile(arguments ...interface{}) interface{} {
-	var stack []nodeInterface
-	argumentsMap := make(map[string]interface{})
-	for i, a := range arguments {
-		argumentsMap[fmt.Sprintf("__ARG__%d", i)] = a
-	}
-	for _, node := range pt.stack {
-		stack = append(stack, nodeInterface{node, []PrimitiveArgs{}})
-		for len(stack[len(stack)-1].args) == stack[len(stack)-1].node.Arity() {
-			var n nodeInterface
-			var res interface{}
-			var err error
-			stack, n = Pop(stack)
-			// here we pass the received values for each argument terminal
-			if ind := slices.Index(maps.Keys(argumentsMap), n.node.Name()); ind > -1 {
-				// argument terminals are always receiving a single value but the interface requires a list
-				res, err = n.node.Eval([]PrimitiveArgs{argumentsMap[n.node.Name()]})
-			} else {
-				res, err = n.node.Eval(n.args)
-			}
-			if err != nil {
-				fmt.Println(err.Error())
-				panic(fmt.Sprintf("eval error for %s", n.node.Name()))
-			}
-			if len(stack) == 0 {
-				return res
-			}
-			stack[len(stack)-1].args = append(stack[len(stack)-1].args, res)
-		}
-	}
-	return nil
+// Compile evaluates the tree with the given values for the argument
+// terminals, panics of the nodes are returned as an EvalError
+func (pt *PrimitiveTree) Compile(arguments ...interface{}) (interface{}, error) {
+	return pt.CompileGuarded(NewEvalGuard(context.Background(), 0), arguments...)
 }
 
 func (pt *PrimitiveTree) ReplaceNodes(nodes []Node) {
@@ -143,15 +122,16 @@ type Node interface {
 	Name() string
 	Eval([]PrimitiveArgs) (interface{}, error)
 	Str([]string) string
-	Ret() reflect.Kind
+	Ret() Type
 	String() string
 }
 
 type Terminal struct {
 	name     string
-	retType  reflect.Kind
+	retType  Type
 	value    interface{}
 	argument bool
+	envValue EnvTerminalFunc
 }
 
 func (t *Terminal) Arity() int {
@@ -163,6 +143,17 @@ func (t *Terminal) Name() string {
 }
 
 func (t *Terminal) Eval(argValues []PrimitiveArgs) (interface{}, error) {
+	return t.EvalEnv(nil, argValues)
+}
+
+// EvalEnv is Eval within the environment of an evaluation, see CompileEnv
+func (t *Terminal) EvalEnv(env any, argValues []PrimitiveArgs) (interface{}, error) {
+	if t.envValue != nil {
+		if env == nil {
+			return nil, noEnvError(t.name)
+		}
+		return t.envValue(env)
+	}
 	if t.argument {
 		if len(argValues) != 1 {
 			return nil, errors.New(
@@ -171,7 +162,7 @@ func (t *Terminal) Eval(argValues []PrimitiveArgs) (interface{}, error) {
 		}
 		switch t.value.(type) {
 		case func():
-			if t.retType != reflect.Func {
+			if kindOf(t.retType) != reflect.Func {
 				return t.value.(func(interface{}) interface{})(argValues[0]), nil
 			}
 			return t.value, nil
@@ -182,7 +173,7 @@ func (t *Terminal) Eval(argValues []PrimitiveArgs) (interface{}, error) {
 	}
 	switch t.value.(type) {
 	case func():
-		if t.retType != reflect.Func {
+		if kindOf(t.retType) != reflect.Func {
 			return t.value.(func() interface{})(), nil
 		}
 		return t.value, nil
@@ -192,12 +183,12 @@ func (t *Terminal) Eval(argValues []PrimitiveArgs) (interface{}, error) {
 }
 
 func (t *Terminal) Str(_ []string) string {
-	if t.argument {
+	if t.argument || t.envValue != nil {
 		return t.Name()
 	}
-	switch t.retType {
+	switch kindOf(t.retType) {
 	case reflect.String:
-		return fmt.Sprintf(`"%s"`, t.value)
+		return strconv.Quote(reflect.ValueOf(t.value).String())
 	case reflect.Func:
 		return t.name
 	default:
@@ -205,7 +196,7 @@ func (t *Terminal) Str(_ []string) string {
 	}
 }
 
-func (t *Terminal) Ret() reflect.Kind {
+func (t *Terminal) Ret() Type {
 	return t.retType
 }
 
@@ -215,31 +206,34 @@ func (t *Terminal) String() string {
 
 var _ Node = new(Terminal)
 
-func NewTerminal(name string, retType reflect.Kind, value interface{}) *Terminal {
+func NewTerminal(name string, retType Type, value interface{}) *Terminal {
 	return &Terminal{
 		name:    name,
-		retType: retType,
+		retType: normalizeType(retType),
 		value:   value,
 	}
 }
 
-func NewArgumentTerminal(name string, retType reflect.Kind) *Terminal {
+func NewArgumentTerminal(name string, retType Type) *Terminal {
 	if match, _ := regexp.MatchString("__ARG__[0-9]+", name); !match {
 		panic("invalid argument name, it has to match __ARG[0-9]+__")
 	}
 	return &Terminal{
 		name:     name,
-		retType:  retType,
+		retType:  normalizeType(retType),
 		argument: true,
 	}
 }
 
 type Primitive struct {
-	name     string
-	function PrimitiveFunc
-	arity    int
-	argTypes []reflect.Kind
-	retType  reflect.Kind
+	name       string
+	function   PrimitiveFunc
+	vectorFunc VectorFunc
+	envFunc    EnvFunc
+	paramTypes []reflect.Type // types the function converts its arguments to, see NewPrimitiveFromFunc
+	arity      int
+	argTypes   []Type
+	retType    Type
 }
 
 func (p *Primitive) Arity() int {
@@ -266,6 +260,11 @@ func (p *Primitive) Equals(o Primitive) bool {
 }
 
 func (p *Primitive) Eval(args []PrimitiveArgs) (interface{}, error) {
+	return p.EvalEnv(nil, args)
+}
+
+// EvalEnv is Eval within the environment of an evaluation, see CompileEnv
+func (p *Primitive) EvalEnv(env any, args []PrimitiveArgs) (interface{}, error) {
 	if len(p.argTypes) > len(args) {
 		return nil, errors.New("not enough arguments")
 	}
@@ -273,19 +272,33 @@ func (p *Primitive) Eval(args []PrimitiveArgs) (interface{}, error) {
 		return nil, errors.New("too many arguments")
 	}
 	for i, arg := range args {
-		if reflect.TypeOf(arg).Kind() != p.argTypes[i] && p.argTypes[i] != reflect.Interface { // lets handle interface as any
-			return nil, errors.New(fmt.Sprintf("%s invalid type for %dth argument (%v) expected %d got %d", p.name, i+1, arg, p.argTypes[i], reflect.TypeOf(arg).Kind()))
+		if acceptsValue(p.argTypes[i], arg) && p.convertible(i, arg) {
+			continue
 		}
-
+		if arg == nil {
+			return nil, errors.New(fmt.Sprintf("%s nil value for %dth argument", p.name, i+1))
+		}
+		return nil, errors.New(fmt.Sprintf("%s invalid type for %dth argument (%v) expected %v got %v", p.name, i+1, arg, p.argTypes[i], reflect.TypeOf(arg)))
+	}
+	if p.envFunc != nil {
+		if env == nil {
+			return nil, noEnvError(p.name)
+		}
+		return p.envFunc(env, args...)
 	}
 	return p.function(args...), nil
 }
 
+// convertible tells if the function can convert the argument to its parameter
+func (p *Primitive) convertible(i int, arg PrimitiveArgs) bool {
+	return p.paramTypes == nil || arg == nil || reflect.TypeOf(arg).ConvertibleTo(p.paramTypes[i])
+}
+
 func (p *Primitive) Str(args []string) string {
 	return fmt.Sprintf("%s(%s)", p.Name(), strings.Join(args, ", "))
 }
 
-func (p *Primitive) Ret() reflect.Kind {
+func (p *Primitive) Ret() Type {
 	return p.retType
 }
 
@@ -295,52 +308,243 @@ func (p *Primitive) String() string {
 
 var _ Node = new(Primitive)
 
-func NewPrimitive(name string, f PrimitiveFunc, argTypes []reflect.Kind, retType reflect.Kind) *Primitive {
+func NewPrimitive(name string, f PrimitiveFunc, argTypes []Type, retType Type) *Primitive {
 	return &Primitive{
 		name:     name,
 		function: f,
 		arity:    len(argTypes),
-		argTypes: argTypes,
-		retType:  retType,
+		argTypes: normalizeTypes(argTypes),
+		retType:  normalizeType(retType),
 	}
 }
 
-// todo create Ephemeral Node types (evaulated when the tree is generated)
+// NewVectorPrimitive creates a primitive that also has an implementation working
+// on whole columns, it is used by PrimitiveTree.EvaluateBatch
+func NewVectorPrimitive(name string, f PrimitiveFunc, vf VectorFunc, argTypes []Type, retType Type) *Primitive {
+	p := NewPrimitive(name, f, argTypes, retType)
+	p.vectorFunc = vf
+	return p
+}
+
+// EphemeralFunc generates the value of an ephemeral constant
+type EphemeralFunc func(*rand.Rand) PrimitiveArgs
+
+// Ephemeral is a terminal whose value is generated when it is put into a tree,
+// the value is kept for the lifetime of that node
+type Ephemeral struct {
+	name      string
+	retType   Type
+	generator EphemeralFunc
+	value     PrimitiveArgs
+}
+
+func (e *Ephemeral) Arity() int {
+	return 0
+}
+
+func (e *Ephemeral) Name() string {
+	return e.name
+}
+
+func (e *Ephemeral) Eval(_ []PrimitiveArgs) (interface{}, error) {
+	return e.value, nil
+}
+
+func (e *Ephemeral) Str(_ []string) string {
+	if kindOf(e.retType) == reflect.String {
+		return strconv.Quote(reflect.ValueOf(e.value).String())
+	}
+	return fmt.Sprintf("%v", e.value)
+}
+
+func (e *Ephemeral) Ret() Type {
+	return e.retType
+}
+
+func (e *Ephemeral) String() string {
+	return e.name
+}
+
+func (e *Ephemeral) Value() PrimitiveArgs {
+	return e.value
+}
+
+// Sample returns a new instance of the ephemeral with a freshly generated value
+func (e *Ephemeral) Sample(r *rand.Rand) *Ephemeral {
+	return e.WithValue(e.generator(r))
+}
+
+func (e *Ephemeral) WithValue(value PrimitiveArgs) *Ephemeral {
+	return &Ephemeral{
+		name:      e.name,
+		retType:   e.retType,
+		generator: e.generator,
+		value:     value,
+	}
+}
+
+var _ Node = new(Ephemeral)
+
+func NewEphemeral(name string, retType Type, generator EphemeralFunc) *Ephemeral {
+	return &Ephemeral{
+		name:      name,
+		retType:   normalizeType(retType),
+		generator: generator,
+	}
+}
 
 // -------------- PrimitiveSet
 
 type PrimitiveSet struct {
-	Primitives map[reflect.Kind][]*Primitive
-	Terminals  map[reflect.Kind][]*Terminal
-	InTypes    []reflect.Kind
-	RetType    reflect.Kind
+	Primitives map[Type][]*Primitive
+	Terminals  map[Type][]*Terminal
+	Ephemerals map[Type][]*Ephemeral
+	InTypes    []Type
+	RetType    Type
 	arity      int
+	weights    map[string]float64
+	types      []Type               // types of the added nodes in order, see subtypesOf
+	reports    map[Type]*TypeReport // cached analyses, dropped when a node is added
+	reportsMu  sync.Mutex
 }
 
 func (ps *PrimitiveSet) AddPrimitive(p *Primitive) {
 	prims := ps.Primitives[p.retType]
 	ps.Primitives[p.Ret()] = append(prims, p)
+	ps.registerType(p.Ret())
+	ps.invalidateReports()
 }
 
 func (ps *PrimitiveSet) AddTerminal(t *Terminal) {
 	terms := ps.Terminals[t.retType]
 	ps.Terminals[t.retType] = append(terms, t)
+	ps.registerType(t.retType)
+	ps.invalidateReports()
 }
 
+// AddEphemeral adds an ephemeral constant, its type has to be a number, bool
+// or string (or a named type of them) so its values can be parsed back
+func (ps *PrimitiveSet) AddEphemeral(e *Ephemeral) {
+	if _, ok := literalType(e.Ret()); !ok {
+		panic(fmt.Sprintf("ephemeral %s has type %v, its values can not be parsed", e.Name(), e.Ret()))
+	}
+	ps.Ephemerals[e.Ret()] = append(ps.Ephemerals[e.Ret()], e)
+	ps.registerType(e.Ret())
+	ps.invalidateReports()
+}
+
+func (ps *PrimitiveSet) AddEphemeralConstant(name string, retType Type, generator EphemeralFunc) {
+	ps.AddEphemeral(NewEphemeral(name, retType, generator))
+}
+
+// SetWeight sets the selection weight of the primitive, terminal or ephemeral
+// with the given name, nodes without a weight have 1 and nodes with 0 are
+// never drawn, negative weights count as 0. Weights are keyed by name, nodes
+// sharing a name across types share their weight. Generation and the mutators
+// read the weights on every draw so they can be changed between generations
+// of a run.
+func (ps *PrimitiveSet) SetWeight(name string, weight float64) {
+	ps.weights[name] = math.Max(weight, 0)
+	ps.invalidateReports()
+}
+
+func (ps *PrimitiveSet) Weight(name string) float64 {
+	if w, ok := ps.weights[name]; ok {
+		return w
+	}
+	return 1
+}
+
+// randomTerminal picks a terminal or ephemeral of the given type, ephemerals
+// are sampled so every occurrence gets its own value
+func (ps *PrimitiveSet) randomTerminal(type_ Type, r *rand.Rand) Node {
+	terms, ephs := ps.terminalsFor(type_)
+	if len(terms)+len(ephs) <= 0 {
+		panic(fmt.Sprintf("No terminal with type: %v available", type_))
+	}
+	nodes := make([]Node, 0, len(terms)+len(ephs))
+	for _, t := range terms {
+		nodes = append(nodes, t)
+	}
+	for _, e := range ephs {
+		nodes = append(nodes, e)
+	}
+	node, ok := weightedChoice(ps, nodes, r)
+	if !ok {
+		panic(fmt.Sprintf("No terminal with type: %v and a positive weight available", type_))
+	}
+	if e, ok := node.(*Ephemeral); ok {
+		return e.Sample(r)
+	}
+	return node
+}
+
+// weightedChoice picks one of the nodes proportionally to their weight in the
+// primitive set, if all weights are equal the choice is uniform. It returns
+// false if no node has a positive weight.
+func weightedChoice[T Node](ps *PrimitiveSet, nodes []T, r *rand.Rand) (T, bool) {
+	total, uniform := 0.0, true
+	for _, n := range nodes {
+		total += ps.Weight(n.Name())
+		uniform = uniform && ps.Weight(n.Name()) == ps.Weight(nodes[0].Name())
+	}
+	if total <= 0 {
+		var none T
+		return none, false
+	}
+	if uniform {
+		return nodes[r.Intn(len(nodes))], true
+	}
+	choice := r.Float64() * total
+	var last T
+	for _, n := range nodes {
+		if ps.Weight(n.Name()) <= 0 {
+			continue
+		}
+		choice -= ps.Weight(n.Name())
+		if choice < 0 {
+			return n, true
+		}
+		last = n
+	}
+	return last, true
+}
+
+// positiveWeight drops the nodes weightedChoice never picks
+func positiveWeight[T Node](ps *PrimitiveSet, nodes []T) []T {
+	var res []T
+	for _, n := range nodes {
+		if ps.Weight(n.Name()) > 0 {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// TerminalRatio is the share of leaf types (of terminals or ephemerals)
+// among the types of all nodes
 func (ps *PrimitiveSet) TerminalRatio() float32 {
-	return float32(len(ps.Terminals)) / float32(len(ps.Terminals)+len(ps.Primitives))
+	leaves := len(ps.Terminals)
+	for k := range ps.Ephemerals {
+		if _, ok := ps.Terminals[k]; !ok {
+			leaves++
+		}
+	}
+	return float32(leaves) / float32(leaves+len(ps.Primitives))
 }
 
-func NewPrimitiveSet(inTypes []reflect.Kind, retType reflect.Kind) *PrimitiveSet {
+func NewPrimitiveSet(inTypes []Type, retType Type) *PrimitiveSet {
 	ps := &PrimitiveSet{
-		Primitives: make(map[reflect.Kind][]*Primitive),
-		Terminals:  make(map[reflect.Kind][]*Terminal),
-		RetType:    retType,
-		InTypes:    inTypes,
+		Primitives: make(map[Type][]*Primitive),
+		Terminals:  make(map[Type][]*Terminal),
+		Ephemerals: make(map[Type][]*Ephemeral),
+		RetType:    normalizeType(retType),
+		InTypes:    normalizeTypes(inTypes),
 		arity:      len(inTypes),
+		weights:    make(map[string]float64),
 	}
 
-	for i, r := range inTypes {
+	for i, r := range ps.InTypes {
 		argName := fmt.Sprintf("__ARG__%d", i)
 		inTerminal := NewArgumentTerminal(argName, r)
 		ps.AddTerminal(inTerminal)
@@ -348,10 +552,18 @@ func NewPrimitiveSet(inTypes []reflect.Kind, retType reflect.Kind) *PrimitiveSet
 	return ps
 }
 
-func GenerateTree(ps *PrimitiveSet, min int, max int, condition GenCondition, type_ reflect.Kind, r *rand.Rand) *PrimitiveTree {
-	var expr []Node
+func GenerateTree(ps *PrimitiveSet, min int, max int, condition GenCondition, type_ Type, r *rand.Rand) *PrimitiveTree {
 	height := r.Intn(max-min) + min
+	return generateTree(ps, height, min, max, condition, type_, r)
+}
 
+// generateTree builds a tree with the condition for an already chosen height.
+// A kind without terminals is closed with the primitives completing it the
+// fastest and primitives with arguments that can not be completed are never
+// chosen, see PrimitiveSet.Validate.
+func generateTree(ps *PrimitiveSet, height int, min int, max int, condition GenCondition, type_ Type, r *rand.Rand) *PrimitiveTree {
+	var expr []Node
+	report := ps.report(type_)
 	stack := []stackItem{
 		{i: 0, t: type_},
 	}
@@ -360,17 +572,18 @@ func GenerateTree(ps *PrimitiveSet, min int, max int, condition GenCondition, ty
 		var item stackItem
 		stack, item = Pop(stack)
 		depth, realType := item.i, item.t
-		if condition(height, depth, min, max, ps) {
-			if len(ps.Terminals[realType]) <= 0 {
-				panic(fmt.Sprintf("No terminal with type: %d available", realType))
+		stop := condition(height, depth, min, max, ps, r)
+		var prim *Primitive
+		found := false
+		if !stop || !ps.hasTerminal(realType) {
+			prim, found = weightedChoice(ps, report.completable(ps, realType, stop), r)
+		}
+		if !found {
+			if !ps.hasTerminal(realType) {
+				panic(fmt.Sprintf("No terminal or primitive can complete type: %s", realType))
 			}
-			term := ps.Terminals[realType][r.Intn(len(ps.Terminals[realType]))]
-			expr = append(expr, term)
+			expr = append(expr, ps.randomTerminal(realType, r))
 		} else {
-			prim := ps.Primitives[realType][r.Intn(len(ps.Primitives[realType]))]
-			if prim == nil {
-				panic("No primitive with type available")
-			}
 			expr = append(expr, prim)
 			for i := len(prim.argTypes) - 1; i >= 0; i-- {
 				stack = append(stack, stackItem{i: depth + 1, t: prim.argTypes[i]})
@@ -380,78 +593,138 @@ func GenerateTree(ps *PrimitiveSet, min int, max int, condition GenCondition, ty
 	return NewPrimitiveTree(expr)
 }
 
-type CrossOver func(PrimitiveTree, PrimitiveTree, *rand.Rand, int) (PrimitiveTree, PrimitiveTree)
+// NoChangeReason tells why a variation operator left its input unchanged
+type NoChangeReason string
+
+const (
+	ReasonTooSmall      NoChangeReason = "tree too small"
+	ReasonNoCommonTypes NoChangeReason = "no common types"
+	ReasonNoCandidate   NoChangeReason = "no candidate node"
+	ReasonNoEphemerals  NoChangeReason = "no ephemeral constants"
+	ReasonLimitExceeded NoChangeReason = "limit exceeded"
+)
+
+// VariationResult describes the outcome of a crossover or mutation
+type VariationResult struct {
+	Changed bool
+	Reason  NoChangeReason
+}
+
+var changed = VariationResult{Changed: true}
+
+func unchanged(reason NoChangeReason) VariationResult {
+	return VariationResult{Reason: reason}
+}
+
+type CrossOver func(PrimitiveTree, PrimitiveTree, *rand.Rand, int) (PrimitiveTree, PrimitiveTree, VariationResult)
+
+// TreeMeasure is the quantity the static limiters compare against their limit
+type TreeMeasure func(*PrimitiveTree) int
+
+var MeasureSize TreeMeasure = func(pt *PrimitiveTree) int {
+	return len(pt.Nodes())
+}
 
-func StaticCrossOverLimiter(crossover CrossOver, limit int) CrossOver {
-	return func(ind1 PrimitiveTree, ind2 PrimitiveTree, r *rand.Rand, bias int) (PrimitiveTree, PrimitiveTree) {
-		child1, child2 := crossover(ind1, ind2, r, bias)
+var MeasureHeight TreeMeasure = func(pt *PrimitiveTree) int {
+	return pt.Height()
+}
+
+// StaticCrossOverLimiter replaces every child whose measure exceeds the limit
+// with one of the parents chosen at random, if both exceed it the parents are
+// returned unchanged
+func StaticCrossOverLimiter(crossover CrossOver, measure TreeMeasure, limit int) CrossOver {
+	return func(ind1 PrimitiveTree, ind2 PrimitiveTree, r *rand.Rand, bias int) (PrimitiveTree, PrimitiveTree, VariationResult) {
+		child1, child2, res := crossover(ind1, ind2, r, bias)
+		if !res.Changed {
+			return child1, child2, res
+		}
 		parents := []PrimitiveTree{ind1, ind2}
-		if len(child1.Nodes()) > limit {
-			child1 = parents[rand.Intn(len(parents))]
+		limited1, limited2 := measure(&child1) > limit, measure(&child2) > limit
+		if limited1 && limited2 {
+			return ind1, ind2, unchanged(ReasonLimitExceeded)
+		}
+		if limited1 {
+			child1 = parents[r.Intn(len(parents))]
 		}
-		if len(child2.Nodes()) > limit {
-			child2 = parents[rand.Intn(len(parents))]
+		if limited2 {
+			child2 = parents[r.Intn(len(parents))]
 		}
-		return child1, child2
+		return child1, child2, res
 	}
 }
 
-func CXOnePoint(ind1 PrimitiveTree, ind2 PrimitiveTree, r *rand.Rand, _ int) (PrimitiveTree, PrimitiveTree) {
+func CXOnePoint(ind1 PrimitiveTree, ind2 PrimitiveTree, r *rand.Rand, _ int) (PrimitiveTree, PrimitiveTree, VariationResult) {
 	if len(ind1.stack) < 2 || len(ind2.stack) < 2 {
-		return ind1, ind2
+		return ind1, ind2, unchanged(ReasonTooSmall)
 	}
+	anyNode := func(Node) bool { return true }
+	return crossSubtrees(ind1, ind2, r, anyNode, anyNode)
+}
 
-	types1 := make(map[reflect.Kind][]int)
+// crossSubtrees swaps two random subtrees of a common type, only the nodes
+// (apart from the roots) accepted by the filters are considered. The subtrees
+// have the exact same type so the swap is valid whatever the subtypes are.
+func crossSubtrees(ind1 PrimitiveTree, ind2 PrimitiveTree, r *rand.Rand, accept1, accept2 func(Node) bool) (PrimitiveTree, PrimitiveTree, VariationResult) {
+	var order []Type
+	types1 := make(map[Type][]int)
 	for i, n := range ind1.stack[1:] {
-		types1[n.Ret()] = append(types1[n.Ret()], i+1)
+		if accept1(n) {
+			if _, ok := types1[n.Ret()]; !ok {
+				order = append(order, n.Ret())
+			}
+			types1[n.Ret()] = append(types1[n.Ret()], i+1)
+		}
 	}
-	types2 := make(map[reflect.Kind][]int)
+	types2 := make(map[Type][]int)
 	for i, n := range ind2.stack[1:] {
-		types2[n.Ret()] = append(types2[n.Ret()], i+1)
+		if accept2(n) {
+			types2[n.Ret()] = append(types2[n.Ret()], i+1)
+		}
 	}
-	// fmt.Printf("t1: %+v, t2: %+v\n", types1, types1)
-
-	commonTypes := Intersect(maps.Keys(types1), maps.Keys(types2))
 
-	if len(commonTypes) > 0 {
-		type_ := commonTypes[r.Intn(len(commonTypes))]
-		index1 := types1[type_][r.Intn(len(types1[type_]))]
-		index2 := types2[type_][r.Intn(len(types2[type_]))]
+	var commonTypes []Type
+	for _, t := range order {
+		if _, ok := types2[t]; ok {
+			commonTypes = append(commonTypes, t)
+		}
+	}
+	if len(commonTypes) == 0 {
+		return ind1, ind2, unchanged(ReasonNoCommonTypes)
+	}
+	type_ := commonTypes[r.Intn(len(commonTypes))]
+	index1 := types1[type_][r.Intn(len(types1[type_]))]
+	index2 := types2[type_][r.Intn(len(types2[type_]))]
 
-		slice1Begin, slice1End := ind1.SearchSubtree(index1)
-		slice2Begin, slice2End := ind2.SearchSubtree(index2)
+	slice1Begin, slice1End := ind1.SearchSubtree(index1)
+	slice2Begin, slice2End := ind2.SearchSubtree(index2)
 
-		child1Stack := ReplaceInRange(ind1.stack, slice1Begin, slice1End, ind2.stack[slice2Begin:slice2End]...)
-		child2Stack := ReplaceInRange(ind2.stack, slice2Begin, slice2End, ind1.stack[slice1Begin:slice1End]...)
-		return *NewPrimitiveTree(child1Stack), *NewPrimitiveTree(child2Stack)
-	}
-	fmt.Println("No common types")
-	return ind1, ind2
+	child1Stack := ReplaceInRange(ind1.stack, slice1Begin, slice1End, ind2.stack[slice2Begin:slice2End]...)
+	child2Stack := ReplaceInRange(ind2.stack, slice2Begin, slice2End, ind1.stack[slice1Begin:slice1End]...)
+	return *NewPrimitiveTree(child1Stack), *NewPrimitiveTree(child2Stack), changed
 }
 
-type Mutator func(*PrimitiveTree) *PrimitiveTree
+type Mutator func(*PrimitiveTree) (*PrimitiveTree, VariationResult)
 
 type MutatorLimiter func(Mutator) Mutator
 
-func StaticMutatorLimiter(mutator Mutator, limit int) Mutator {
-	return func(ind *PrimitiveTree) *PrimitiveTree {
-		res := mutator(ind)
-		if len(res.Nodes()) > limit {
-			res = ind
+// StaticMutatorLimiter keeps the original tree if the measure of the mutant exceeds the limit
+func StaticMutatorLimiter(mutator Mutator, measure TreeMeasure, limit int) Mutator {
+	return func(ind *PrimitiveTree) (*PrimitiveTree, VariationResult) {
+		mutant, res := mutator(ind)
+		if res.Changed && measure(mutant) > limit {
+			return ind, unchanged(ReasonLimitExceeded)
 		}
-		return res
+		return mutant, res
 	}
 }
 
-//todo mutNodeReplacement mutEphemeral mutInsert mutShrink
-
 type UniformMutator struct {
-	expr func(*PrimitiveSet, reflect.Kind) []Node
+	expr func(*PrimitiveSet, Type) []Node
 	ps   *PrimitiveSet
 	r    *rand.Rand
 }
 
-func NewUniformMutator(ps *PrimitiveSet, expr func(*PrimitiveSet, reflect.Kind) []Node, r *rand.Rand) *UniformMutator {
+func NewUniformMutator(ps *PrimitiveSet, expr func(*PrimitiveSet, Type) []Node, r *rand.Rand) *UniformMutator {
 	return &UniformMutator{
 		expr: expr,
 		r:    r,
@@ -459,12 +732,12 @@ func NewUniformMutator(ps *PrimitiveSet, expr func(*PrimitiveSet, reflect.Kind)
 	}
 }
 
-func (m *UniformMutator) Mutate(ind *PrimitiveTree) *PrimitiveTree {
+func (m *UniformMutator) Mutate(ind *PrimitiveTree) (*PrimitiveTree, VariationResult) {
 	index := m.r.Intn(len(ind.stack))
 	sliceStart, sliceEnd := ind.SearchSubtree(index)
 	type_ := ind.stack[index].Ret()
 	newNodes := m.expr(m.ps, type_)
-	return NewPrimitiveTree(ReplaceInRange(ind.stack, sliceStart, sliceEnd, newNodes...))
+	return NewPrimitiveTree(ReplaceInRange(ind.stack, sliceStart, sliceEnd, newNodes...)), changed
 }
 
 type Fitness struct {
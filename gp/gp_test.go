@@ -17,8 +17,8 @@ var func2 PrimitiveFunc = func(a ...PrimitiveArgs) PrimitiveArgs {
 	return strings.Repeat(a[0].(string), a[1].(int))
 }
 
-var prim1 = NewPrimitive("prim1", func1, []reflect.Kind{reflect.Int, reflect.String}, reflect.Int)
-var prim2 = NewPrimitive("prim2", func2, []reflect.Kind{reflect.String, reflect.Int}, reflect.String)
+var prim1 = NewPrimitive("prim1", func1, []Type{reflect.Int, reflect.String}, reflect.Int)
+var prim2 = NewPrimitive("prim2", func2, []Type{reflect.String, reflect.Int}, reflect.String)
 var term1 = NewTerminal("term1", reflect.Int, 4)
 var term2 = NewTerminal("term2", reflect.String, "hello")
 
@@ -35,7 +35,7 @@ func getValidNodes2() []Node {
 }
 
 func getPrimitiveSet() *PrimitiveSet {
-	ps := NewPrimitiveSet([]reflect.Kind{}, reflect.Int)
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
 	ps.AddPrimitive(prim1)
 	ps.AddPrimitive(prim2)
 	ps.AddTerminal(term1)
@@ -44,6 +44,12 @@ func getPrimitiveSet() *PrimitiveSet {
 	return ps
 }
 
+func mustCompile(t *testing.T, tree *PrimitiveTree, args ...interface{}) interface{} {
+	res, err := tree.Compile(args...)
+	assert.NoError(t, err)
+	return res
+}
+
 func TestPrimitiveTreeString(t *testing.T) {
 	tree := NewPrimitiveTree(getValidNodes())
 	assert.Equal(t, `prim1(4, prim2("hello", 4))`, tree.String())
@@ -51,7 +57,7 @@ func TestPrimitiveTreeString(t *testing.T) {
 
 func TestPrimitiveTreeCompile(t *testing.T) {
 	tree := NewPrimitiveTree(getValidNodes())
-	assert.Equal(t, 5*4*4, tree.Compile().(int))
+	assert.Equal(t, 5*4*4, mustCompile(t, tree).(int))
 }
 
 func TestPrimitiveTreeRoot(t *testing.T) {
@@ -111,28 +117,30 @@ func TestGenerateTree(t *testing.T) {
 	assert.LessOrEqual(t, tree1.Height(), 5)
 	assert.GreaterOrEqual(t, tree2.Height(), 3)
 	assert.LessOrEqual(t, tree2.Height(), 5)
-	assert.NotPanics(t, func() { tree1.Compile() })
-	assert.NotPanics(t, func() { tree2.Compile() })
+	mustCompile(t, tree1)
+	mustCompile(t, tree2)
 }
 
 func TestCompileWithArguments(t *testing.T) {
 	r := rand.New(rand.NewSource(1001))
 
-	ps := NewPrimitiveSet([]reflect.Kind{reflect.Int, reflect.String, reflect.Int}, reflect.Int)
+	ps := NewPrimitiveSet([]Type{reflect.Int, reflect.String, reflect.Int}, reflect.Int)
 	ps.AddPrimitive(prim1)
 	ps.AddPrimitive(prim2)
 
 	assert.Equal(t, 2, len(ps.Terminals[reflect.Int]))
 	assert.Equal(t, 1, len(ps.Terminals[reflect.String]))
 	tree := GenerateTree(ps, 2, 3, GenFull, ps.RetType, r)
-	assert.NotPanics(t, func() { tree.Compile(4, "aloha", 2) })
-	assert.Equal(t, 0, tree.Compile(1, "", 1))
-	// panic if not enough argument
-	assert.Panics(t, func() { tree.Compile(4, "aloha") })
-	// panic if type mismtach
-	assert.Panics(t, func() { tree.Compile(true, "aloha", 1) })
-	// no panic if extra arguments
-	assert.NotPanics(t, func() { tree.Compile(1, "aloha", 1, 12) })
+	mustCompile(t, tree, 4, "aloha", 2)
+	assert.Equal(t, 0, mustCompile(t, tree, 1, "", 1))
+	// error if not enough argument
+	_, err := tree.Compile(4, "aloha")
+	assert.Error(t, err)
+	// error if type mismtach
+	_, err = tree.Compile(true, "aloha", 1)
+	assert.Error(t, err)
+	// no error if extra arguments
+	mustCompile(t, tree, 1, "aloha", 1, 12)
 
 }
 
@@ -142,33 +150,46 @@ func TestUniformMutator(t *testing.T) {
 	ps := getPrimitiveSet()
 	origLen := len(tree.Nodes())
 	beforeMut := fmt.Sprintf("%s", tree)
-	uniformMutator := NewUniformMutator(ps, func(ps *PrimitiveSet, type_ reflect.Kind) []Node {
+	uniformMutator := NewUniformMutator(ps, func(ps *PrimitiveSet, type_ Type) []Node {
 		return GenerateTree(ps, 1, 2, GenGrow, type_, r).Nodes()
 	}, r)
-	tree = uniformMutator.Mutate(tree)
+	tree, _ = uniformMutator.Mutate(tree)
 	assert.Len(t, tree.Nodes(), origLen+2) // adds 3 nodes and removes one
 	assert.NotEqual(t, beforeMut, fmt.Sprintf("%s", tree))
 	assert.Equal(t, `prim1(4, prim2(prim2("hello", 4), 4))`, fmt.Sprintf("%s", tree))
-	assert.NotPanics(t, func() { tree.Compile() })
+	mustCompile(t, tree)
 }
 
 func TestCXOnePointestCXOnePoint(t *testing.T) {
 	tree1 := &PrimitiveTree{
 		stack: []Node{prim1, prim1, prim1, term1, term2, prim2, term2, term1, prim2, term2, prim1, term1, term2}}
-	assert.NotPanics(t, func() { tree1.Compile() })
+	mustCompile(t, tree1)
 	tree2 := &PrimitiveTree{
 		stack: []Node{prim2, prim2, term2, term1, prim1, term1, term2},
 	}
-	assert.NotPanics(t, func() { tree2.Compile() })
+	mustCompile(t, tree2)
 
 	r := rand.New(rand.NewSource(715))
-	t1, t2 := CXOnePoint(*tree1, *tree2, r, 0) // node at index 3 in tree1 will be replaced with node index 4:7 in tree2
+	t1, t2, _ := CXOnePoint(*tree1, *tree2, r, 0) // node at index 3 in tree1 will be replaced with node index 4:7 in tree2
 	tree1.ReplaceNodes(t1.Nodes())
 	tree2.ReplaceNodes(t2.Nodes())
 	assert.Equal(t, []Node{prim1, prim1, prim1, prim1, term1, term2, term2, prim2, term2, term1, prim2, term2, prim1, term1, term2}, tree1.stack)
 	assert.Equal(t, []Node{prim2, prim2, term2, term1, term1}, tree2.stack)
-	assert.NotPanics(t, func() { tree1.Compile() })
-	assert.NotPanics(t, func() { tree2.Compile() })
+	mustCompile(t, tree1)
+	mustCompile(t, tree2)
+}
+
+func TestCXOnePointNoCommonTypes(t *testing.T) {
+	r := rand.New(rand.NewSource(715))
+	tree1 := PrimitiveTree{stack: []Node{prim1, term1, term2}}
+	_, _, res := CXOnePoint(tree1, PrimitiveTree{stack: []Node{term1}}, r, 0)
+	assert.Equal(t, unchanged(ReasonTooSmall), res)
+
+	funcTree, _ := ParseTree("prog2(turn_left, turn_right)", getFuncPrimitiveSet())
+	child1, child2, res := CXOnePoint(tree1, *funcTree, r, 0)
+	assert.Equal(t, unchanged(ReasonNoCommonTypes), res)
+	assert.Equal(t, tree1.Nodes(), child1.Nodes())
+	assert.Equal(t, funcTree.Nodes(), child2.Nodes())
 }
 
 func TestStaticCrossOverLimiter(t *testing.T) {
@@ -177,7 +198,7 @@ func TestStaticCrossOverLimiter(t *testing.T) {
 	tree1 := GenerateTree(ps, 3, 4, GenFull, ps.RetType, r)
 	tree2 := GenerateTree(ps, 3, 4, GenFull, ps.RetType, r)
 	for i := 0; i < 10; i++ {
-		t1, t2 := StaticCrossOverLimiter(CXOnePoint, 17)(*tree1, *tree2, r, 0)
+		t1, t2, _ := StaticCrossOverLimiter(CXOnePoint, MeasureSize, 17)(*tree1, *tree2, r, 0)
 		tree1.ReplaceNodes(t1.Nodes())
 		tree2.ReplaceNodes(t2.Nodes())
 		assert.Less(t, len(tree1.Nodes()), 18)
@@ -189,13 +210,169 @@ func TestStaticMutatorLimiter(t *testing.T) {
 	ps := getPrimitiveSet()
 	r := rand.New(rand.NewSource(444))
 	tree := GenerateTree(ps, 3, 4, GenFull, ps.RetType, r)
-	uniformMutator := NewUniformMutator(ps, func(ps *PrimitiveSet, type_ reflect.Kind) []Node {
+	uniformMutator := NewUniformMutator(ps, func(ps *PrimitiveSet, type_ Type) []Node {
 		return GenerateTree(ps, 2, 3, GenGrow, type_, r).Nodes()
 	}, r)
 	for i := 0; i < 10; i++ {
-		tree = StaticMutatorLimiter(uniformMutator.Mutate, 17)(tree)
+		tree, _ = StaticMutatorLimiter(uniformMutator.Mutate, MeasureSize, 17)(tree)
 		assert.Less(t, len(tree.Nodes()), 18)
 	}
 }
 
+func TestStaticLimitersHeight(t *testing.T) {
+	ps := getPrimitiveSet()
+	r := rand.New(rand.NewSource(444))
+	tree1 := GenerateTree(ps, 3, 4, GenFull, ps.RetType, r)
+	tree2 := GenerateTree(ps, 3, 4, GenFull, ps.RetType, r)
+	crossover := StaticCrossOverLimiter(CXOnePoint, MeasureHeight, 4)
+	uniformMutator := NewUniformMutator(ps, func(ps *PrimitiveSet, type_ Type) []Node {
+		return GenerateTree(ps, 2, 3, GenFull, type_, r).Nodes()
+	}, r)
+	mutator := StaticMutatorLimiter(uniformMutator.Mutate, MeasureHeight, 4)
+	for i := 0; i < 20; i++ {
+		t1, t2, _ := crossover(*tree1, *tree2, r, 0)
+		tree1.ReplaceNodes(t1.Nodes())
+		mutant, _ := mutator(&t2)
+		tree2.ReplaceNodes(mutant.Nodes())
+		assert.LessOrEqual(t, tree1.Height(), 4)
+		assert.LessOrEqual(t, tree2.Height(), 4)
+	}
+}
+
+func TestStaticCrossOverLimiterFallback(t *testing.T) {
+	tree1 := NewPrimitiveTree(getValidNodes())
+	tree2 := NewPrimitiveTree(getValidNodes2())
+	limited := StaticCrossOverLimiter(func(ind1, ind2 PrimitiveTree, _ *rand.Rand, _ int) (PrimitiveTree, PrimitiveTree, VariationResult) {
+		big := NewPrimitiveTree(append(append([]Node{}, ind1.stack...), ind2.stack...))
+		return *big, *big, changed
+	}, MeasureSize, 5)
+	run := func() []string {
+		r := rand.New(rand.NewSource(12))
+		res := []string{}
+		for i := 0; i < 10; i++ {
+			t1, t2, result := limited(*tree1, *tree2, r, 0)
+			assert.Equal(t, unchanged(ReasonLimitExceeded), result)
+			assert.Equal(t, tree1.String(), t1.String())
+			assert.Equal(t, tree2.String(), t2.String())
+			res = append(res, t1.String(), t2.String())
+		}
+		return res
+	}
+	assert.Equal(t, run(), run())
+}
+
+func TestEphemeralConstant(t *testing.T) {
+	ps := getPrimitiveSet()
+	ps.AddEphemeralConstant("rand101", reflect.Int, func(r *rand.Rand) PrimitiveArgs {
+		return r.Intn(101)
+	})
+	r := rand.New(rand.NewSource(71))
+	tree := GenerateTree(ps, 2, 4, GenFull, ps.RetType, r)
+
+	ephemerals := []*Ephemeral{}
+	for _, n := range tree.Nodes() {
+		if e, ok := n.(*Ephemeral); ok {
+			ephemerals = append(ephemerals, e)
+		}
+	}
+	assert.NotEmpty(t, ephemerals)
+	for _, e := range ephemerals {
+		assert.Equal(t, "rand101", e.Name())
+		assert.Equal(t, fmt.Sprintf("%d", e.Value()), e.Str(nil))
+		assert.Contains(t, tree.String(), e.Str(nil))
+	}
+
+	// values are kept by copies and do not change between evaluations
+	copied := NewPrimitiveTree(tree.Nodes())
+	assert.Equal(t, tree.String(), copied.String())
+	assert.Equal(t, mustCompile(t, tree), mustCompile(t, copied))
+
+	sampled := ephemerals[0].Sample(r)
+	assert.Equal(t, ephemerals[0].Name(), sampled.Name())
+	assert.NotSame(t, ephemerals[0], sampled)
+}
+
 // TODO fitness tests
+
+func TestPrimitiveSetWeights(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	r := rand.New(rand.NewSource(19))
+	count := func() map[string]int {
+		counts := map[string]int{}
+		for i := 0; i < 50; i++ {
+			for _, n := range GenerateTree(ps, 2, 4, GenFull, ps.RetType, r).Nodes() {
+				counts[n.Name()]++
+			}
+		}
+		return counts
+	}
+	assert.Equal(t, 1.0, ps.Weight("prog3"))
+	ps.SetWeight("prog3", 0)
+	ps.SetWeight("if_food_ahead", 10)
+	ps.SetWeight("turn_left", 0)
+	counts := count()
+	assert.Zero(t, counts["prog3"])
+	assert.Zero(t, counts["turn_left"])
+	assert.Greater(t, counts["if_food_ahead"], 5*counts["prog2"])
+
+	// weights can be changed between generations
+	ps.SetWeight("prog3", 1)
+	ps.SetWeight("if_food_ahead", 0)
+	counts = count()
+	assert.Greater(t, counts["prog3"], 0)
+	assert.Zero(t, counts["if_food_ahead"])
+}
+
+func TestPrimitiveSetZeroWeights(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	r := rand.New(rand.NewSource(23))
+	for _, name := range []string{"prog3", "prog2", "if_food_ahead"} {
+		ps.SetWeight(name, 0)
+	}
+	_, ok := weightedChoice(ps, ps.Primitives[reflect.Func], r)
+	assert.False(t, ok)
+	for i := 0; i < 20; i++ {
+		assert.Len(t, GenerateTree(ps, 2, 4, GenFull, ps.RetType, r).Nodes(), 1)
+	}
+
+	ps.SetWeight("move_forward", 0)
+	ps.SetWeight("turn_left", 0)
+	ps.SetWeight("turn_right", 0)
+	assert.Panics(t, func() { GenerateTree(ps, 2, 4, GenFull, ps.RetType, r) })
+}
+
+func TestTerminalRatioEphemerals(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	ps.AddPrimitive(NewPrimitive("add", func(a ...PrimitiveArgs) PrimitiveArgs {
+		return a[0].(int) + a[1].(int)
+	}, []Type{reflect.Int, reflect.Int}, reflect.Int))
+	ps.AddEphemeralConstant("rand_int", reflect.Int, func(r *rand.Rand) PrimitiveArgs { return r.Intn(10) })
+	assert.Equal(t, float32(0.5), ps.TerminalRatio())
+
+	// GenGrow stops early on ephemeral leaves too
+	r := rand.New(rand.NewSource(7))
+	smaller := 0
+	for i := 0; i < 20; i++ {
+		if len(generateTree(ps, 3, 1, 4, GenGrow, ps.RetType, r).Nodes()) < 15 {
+			smaller++
+		}
+	}
+	assert.Greater(t, smaller, 0)
+}
+
+func TestPrimitiveSetNegativeWeight(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	for i, name := range []string{"a", "b", "c"} {
+		ps.AddTerminal(NewTerminal(name, reflect.Int, i))
+	}
+	ps.SetWeight("b", -1)
+	ps.SetWeight("c", 2)
+	assert.Equal(t, 0.0, ps.Weight("b"))
+	r := rand.New(rand.NewSource(29))
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[ps.randomTerminal(reflect.Int, r).Name()]++
+	}
+	assert.Zero(t, counts["b"])
+	assert.Greater(t, counts["c"], counts["a"])
+}
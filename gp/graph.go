@@ -0,0 +1,158 @@
+package gp
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// NodeRange is a [Begin, End) range of node indices as returned by SearchSubtree
+type NodeRange struct {
+	Begin int
+	End   int
+}
+
+func (nr NodeRange) contains(i int) bool {
+	return i >= nr.Begin && i < nr.End
+}
+
+func highlighted(i int, highlights []NodeRange) bool {
+	for _, h := range highlights {
+		if h.contains(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// Graph returns the node indices, the parent-child edges and the labels of
+// the tree, the same structure DEAP's gp.graph produces
+func Graph(pt *PrimitiveTree) ([]int, [][2]int, map[int]string) {
+	nodes := make([]int, len(pt.stack))
+	edges := [][2]int{}
+	labels := make(map[int]string)
+	type parent struct {
+		index     int
+		remaining int
+	}
+	var stack []parent
+	for i, node := range pt.stack {
+		nodes[i] = i
+		if node.Arity() > 0 {
+			labels[i] = node.Name()
+		} else {
+			labels[i] = node.Str(nil)
+		}
+		if len(stack) > 0 {
+			edges = append(edges, [2]int{stack[len(stack)-1].index, i})
+			stack[len(stack)-1].remaining--
+		}
+		stack = append(stack, parent{i, node.Arity()})
+		for len(stack) > 0 && stack[len(stack)-1].remaining == 0 {
+			stack, _ = Pop(stack)
+		}
+	}
+	return nodes, edges, labels
+}
+
+// WriteDOT writes the tree in Graphviz DOT format, nodes in any of the
+// highlight ranges are filled
+func WriteDOT(w io.Writer, pt *PrimitiveTree, highlights ...NodeRange) error {
+	nodes, edges, labels := Graph(pt)
+	var b strings.Builder
+	b.WriteString("digraph tree {\n")
+	for _, n := range nodes {
+		style := ""
+		if highlighted(n, highlights) {
+			style = `, style=filled, fillcolor="lightblue"`
+		}
+		fmt.Fprintf(&b, "\t%d [label=%q%s];\n", n, labels[n], style)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "\t%d -> %d;\n", e[0], e[1])
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+const (
+	svgMargin     = 10
+	svgLevel      = 60
+	svgNodeHeight = 24
+	svgCharWidth  = 8
+	svgPadding    = 16
+)
+
+type svgNode struct {
+	x     float64
+	y     float64
+	width float64
+	label string
+}
+
+// layoutSVG places the leaves next to each other from left to right and
+// centres every primitive above its children
+func layoutSVG(pt *PrimitiveTree) ([]svgNode, [][2]int, float64, float64) {
+	_, edges, labels := Graph(pt)
+	layout := make([]svgNode, len(pt.stack))
+	children := make([][]int, len(pt.stack))
+	for _, e := range edges {
+		children[e[0]] = append(children[e[0]], e[1])
+	}
+	nextX := float64(svgMargin)
+	maxY := 0.0
+	var place func(i, depth int)
+	place = func(i, depth int) {
+		label := labels[i]
+		width := float64(len(label)*svgCharWidth + svgPadding)
+		for _, c := range children[i] {
+			place(c, depth+1)
+		}
+		x := 0.0
+		if len(children[i]) == 0 {
+			x = nextX + width/2
+			nextX += width + svgMargin
+		} else {
+			first, last := layout[children[i][0]], layout[children[i][len(children[i])-1]]
+			x = (first.x + last.x) / 2
+		}
+		y := float64(svgMargin+svgNodeHeight/2) + float64(depth*svgLevel)
+		if y > maxY {
+			maxY = y
+		}
+		layout[i] = svgNode{x: x, y: y, width: width, label: label}
+	}
+	if len(pt.stack) > 0 {
+		place(0, 0)
+	}
+	return layout, edges, nextX, maxY + svgNodeHeight/2 + svgMargin
+}
+
+// WriteSVG renders the tree as a self-contained SVG image, nodes in any of
+// the highlight ranges are filled
+func WriteSVG(w io.Writer, pt *PrimitiveTree, highlights ...NodeRange) error {
+	layout, edges, width, height := layoutSVG(pt)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`+"\n", width, height, width, height)
+	b.WriteString(`<g stroke="black" stroke-width="1">` + "\n")
+	for _, e := range edges {
+		from, to := layout[e[0]], layout[e[1]]
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"/>`+"\n", from.x, from.y, to.x, to.y)
+	}
+	b.WriteString("</g>\n")
+	b.WriteString(`<g font-family="monospace" font-size="13" text-anchor="middle" dominant-baseline="central">` + "\n")
+	for i, n := range layout {
+		fill := "white"
+		if highlighted(i, highlights) {
+			fill = "lightblue"
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%d" rx="6" fill="%s" stroke="black"/>`+"\n",
+			n.x-n.width/2, n.y-svgNodeHeight/2, n.width, svgNodeHeight, fill)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f">%s</text>`+"\n", n.x, n.y, html.EscapeString(n.label))
+	}
+	b.WriteString("</g>\n</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
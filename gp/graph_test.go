@@ -0,0 +1,61 @@
+package gp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph(t *testing.T) {
+	nodes, edges, labels := Graph(NewPrimitiveTree(getValidNodes()))
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, nodes)
+	assert.Equal(t, [][2]int{{0, 1}, {0, 2}, {2, 3}, {2, 4}}, edges)
+	assert.Equal(t, map[int]string{0: "prim1", 1: "4", 2: "prim2", 3: `"hello"`, 4: "4"}, labels)
+}
+
+func TestWriteDOT(t *testing.T) {
+	tree := NewPrimitiveTree(getValidNodes())
+	begin, end := tree.SearchSubtree(2)
+	var b bytes.Buffer
+	assert.NoError(t, WriteDOT(&b, tree, NodeRange{begin, end}))
+	assert.Equal(t, `digraph tree {
+	0 [label="prim1"];
+	1 [label="4"];
+	2 [label="prim2", style=filled, fillcolor="lightblue"];
+	3 [label="\"hello\"", style=filled, fillcolor="lightblue"];
+	4 [label="4", style=filled, fillcolor="lightblue"];
+	0 -> 1;
+	0 -> 2;
+	2 -> 3;
+	2 -> 4;
+}
+`, b.String())
+}
+
+func TestWriteSVG(t *testing.T) {
+	tree := NewPrimitiveTree(getValidNodes())
+	var b bytes.Buffer
+	assert.NoError(t, WriteSVG(&b, tree, NodeRange{1, 2}))
+	svg := b.String()
+	assert.Equal(t, 5, strings.Count(svg, "<rect"))
+	assert.Equal(t, 4, strings.Count(svg, "<line"))
+	assert.Equal(t, 1, strings.Count(svg, "lightblue"))
+	assert.Contains(t, svg, "&#34;hello&#34;")
+
+	// has to be well formed xml
+	decoder := xml.NewDecoder(&b)
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		if err != nil {
+			break
+		}
+	}
+}
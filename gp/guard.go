@@ -0,0 +1,161 @@
+package gp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EvalFailure tells why a guarded evaluation was stopped
+type EvalFailure string
+
+const (
+	FailureBudget   EvalFailure = "budget exceeded"
+	FailureDeadline EvalFailure = "deadline exceeded"
+	FailurePanic    EvalFailure = "panic"
+	FailureEval     EvalFailure = "eval error"   // a node returned an error
+	FailureInvalid  EvalFailure = "invalid tree" // the tree is not complete
+)
+
+// EvalError is returned by guarded evaluations, Node is the node being
+// evaluated when it was stopped if known
+type EvalError struct {
+	Failure EvalFailure
+	Node    string
+	Err     error
+}
+
+func (e *EvalError) Error() string {
+	if e.Node == "" {
+		return fmt.Sprintf("%s: %s", e.Failure, e.Err.Error())
+	}
+	return fmt.Sprintf("%s at %s: %s", e.Failure, e.Node, e.Err.Error())
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// EvalGuard limits a single evaluation to a number of node evaluations and to
+// the lifetime of a context. The context is only checked in Step, between two
+// nodes, so a primitive that blocks can not be interrupted. It is not safe for
+// concurrent use.
+type EvalGuard struct {
+	ctx      context.Context
+	maxNodes int
+	used     int
+}
+
+// NewEvalGuard creates a guard, maxNodes 0 means no limit on node evaluations
+func NewEvalGuard(ctx context.Context, maxNodes int) *EvalGuard {
+	return &EvalGuard{
+		ctx:      ctx,
+		maxNodes: maxNodes,
+	}
+}
+
+// Used returns the number of steps taken so far
+func (g *EvalGuard) Used() int {
+	return g.used
+}
+
+// Step accounts for the evaluation of a node, domains running the compiled
+// program themselves (like the ant's loop) should call it for every step too
+func (g *EvalGuard) Step(node string) error {
+	g.used++
+	if g.maxNodes > 0 && g.used > g.maxNodes {
+		return &EvalError{Failure: FailureBudget, Node: node, Err: errors.New(fmt.Sprintf("more than %d node evaluations", g.maxNodes))}
+	}
+	if err := g.ctx.Err(); err != nil {
+		return &EvalError{Failure: FailureDeadline, Node: node, Err: err}
+	}
+	return nil
+}
+
+// Call runs f turning its panics into an EvalError
+func (g *EvalGuard) Call(f func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = panicError("", p)
+		}
+	}()
+	return f()
+}
+
+func panicError(node string, p any) error {
+	err, ok := p.(error)
+	if !ok {
+		err = errors.New(fmt.Sprint(p))
+	}
+	return &EvalError{Failure: FailurePanic, Node: node, Err: err}
+}
+
+// CompileGuarded is Compile charging every node evaluation to the guard, a
+// panicking node is reported as an EvalError instead of crashing the run
+func (pt *PrimitiveTree) CompileGuarded(g *EvalGuard, arguments ...interface{}) (interface{}, error) {
+	return pt.CompileEnv(g, nil, arguments...)
+}
+
+// CompileEnv is CompileGuarded passing env to the primitives and terminals
+// created with NewEnvPrimitive and NewEnvTerminal
+func (pt *PrimitiveTree) CompileEnv(g *EvalGuard, env any, arguments ...interface{}) (res interface{}, err error) {
+	var stack []nodeInterface
+	argumentsMap := make(map[string]interface{})
+	for i, a := range arguments {
+		argumentsMap[fmt.Sprintf("__ARG__%d", i)] = a
+	}
+	current := ""
+	defer func() {
+		if p := recover(); p != nil {
+			res, err = nil, panicError(current, p)
+		}
+	}()
+	for _, node := range pt.stack {
+		stack = append(stack, nodeInterface{node, []PrimitiveArgs{}})
+		for len(stack[len(stack)-1].args) == stack[len(stack)-1].node.Arity() {
+			var n nodeInterface
+			stack, n = Pop(stack)
+			current = n.node.Name()
+			if err := g.Step(current); err != nil {
+				return nil, err
+			}
+			// here we pass the received values for each argument terminal
+			if value, ok := argumentsMap[n.node.Name()]; ok {
+				// argument terminals are always receiving a single value but the interface requires a list
+				res, err = n.node.Eval([]PrimitiveArgs{value})
+			} else if en, ok := n.node.(envNode); ok {
+				res, err = en.EvalEnv(env, n.args)
+			} else {
+				res, err = n.node.Eval(n.args)
+			}
+			if err != nil {
+				return nil, &EvalError{Failure: FailureEval, Node: n.node.Name(), Err: err}
+			}
+			if len(stack) == 0 {
+				return res, nil
+			}
+			stack[len(stack)-1].args = append(stack[len(stack)-1].args, res)
+		}
+	}
+	return nil, &EvalError{Failure: FailureInvalid, Err: errors.New("incomplete tree")}
+}
+
+// GuardedEval adapts an evaluation using a guard to the evaluation function
+// of EaSimple. Every call gets a fresh guard with the node budget and the
+// timeout (0 for none), if the evaluation fails the individual gets the
+// fitness values penalty returns for the error.
+func GuardedEval(eval func(Individual, *EvalGuard) error, maxNodes int, timeout time.Duration, penalty func(error) []float32) func(Individual) {
+	return func(ind Individual) {
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		g := NewEvalGuard(ctx, maxNodes)
+		if err := g.Call(func() error { return eval(ind, g) }); err != nil {
+			ind.Fitness().SetValues(penalty(err))
+		}
+	}
+}
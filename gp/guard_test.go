@@ -0,0 +1,104 @@
+package gp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileGuardedBudget(t *testing.T) {
+	tree := NewPrimitiveTree(getValidNodes())
+	res, err := tree.CompileGuarded(NewEvalGuard(context.Background(), 5))
+	assert.NoError(t, err)
+	assert.Equal(t, 5*4*4, res)
+
+	_, err = tree.CompileGuarded(NewEvalGuard(context.Background(), 4))
+	assert.Equal(t, &EvalError{Failure: FailureBudget, Node: "prim1", Err: errors.New("more than 4 node evaluations")}, err)
+	assert.EqualError(t, err, "budget exceeded at prim1: more than 4 node evaluations")
+}
+
+func TestCompileGuardedDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewPrimitiveTree(getValidNodes()).CompileGuarded(NewEvalGuard(ctx, 0))
+	var evalErr *EvalError
+	assert.ErrorAs(t, err, &evalErr)
+	assert.Equal(t, FailureDeadline, evalErr.Failure)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCompilePanic(t *testing.T) {
+	boom := NewPrimitive("boom", func(a ...PrimitiveArgs) PrimitiveArgs {
+		return a[0].(int) / a[1].(int)
+	}, []Type{reflect.Int, reflect.Int}, reflect.Int)
+	zero := NewTerminal("zero", reflect.Int, 0)
+	_, err := NewPrimitiveTree([]Node{boom, term1, zero}).Compile()
+	var evalErr *EvalError
+	assert.ErrorAs(t, err, &evalErr)
+	assert.Equal(t, FailurePanic, evalErr.Failure)
+	assert.Equal(t, "boom", evalErr.Node)
+	assert.EqualError(t, err, "panic at boom: runtime error: integer divide by zero")
+}
+
+func TestCompileFailures(t *testing.T) {
+	_, err := NewPrimitiveTree([]Node{prim1, term1}).Compile()
+	assert.Equal(t, &EvalError{Failure: FailureInvalid, Err: errors.New("incomplete tree")}, err)
+	assert.EqualError(t, err, "invalid tree: incomplete tree")
+
+	_, err = NewPrimitiveTree([]Node{prim1, term1, term1}).Compile()
+	var evalErr *EvalError
+	assert.ErrorAs(t, err, &evalErr)
+	assert.Equal(t, FailureEval, evalErr.Failure)
+	assert.Equal(t, "prim1", evalErr.Node)
+	assert.EqualError(t, err, "eval error at prim1: prim1 invalid type for 2th argument (4) expected string got int")
+}
+
+func TestGuardedEval(t *testing.T) {
+	penalty := func(err error) []float32 {
+		var evalErr *EvalError
+		if errors.As(err, &evalErr) && evalErr.Failure == FailureDeadline {
+			return []float32{-2}
+		}
+		return []float32{-1}
+	}
+	newInd := func() Individual {
+		fit, _ := NewFitness([]float32{1})
+		return &IndividualImpl{tree: NewPrimitiveTree(getValidNodes()), fitness: fit}
+	}
+	var used int
+	eval := GuardedEval(func(ind Individual, g *EvalGuard) error {
+		res, err := ind.Tree().CompileGuarded(g)
+		used = g.Used()
+		if err != nil {
+			return err
+		}
+		ind.Fitness().SetValues([]float32{float32(res.(int))})
+		return nil
+	}, 5, 0, penalty)
+	ind := newInd()
+	eval(ind)
+	assert.Equal(t, []float32{80}, ind.Fitness().GetValues())
+	// every evaluation gets a fresh budget
+	eval(ind)
+	assert.Equal(t, 5, used)
+
+	ind = newInd()
+	GuardedEval(func(ind Individual, g *EvalGuard) error {
+		panic("broken evaluation")
+	}, 0, 0, penalty)(ind)
+	assert.Equal(t, []float32{-1}, ind.Fitness().GetValues())
+
+	ind = newInd()
+	GuardedEval(func(ind Individual, g *EvalGuard) error {
+		for {
+			if err := g.Step("loop"); err != nil {
+				return err
+			}
+		}
+	}, 0, time.Millisecond, penalty)(ind)
+	assert.Equal(t, []float32{-2}, ind.Fitness().GetValues())
+}
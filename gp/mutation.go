@@ -0,0 +1,201 @@
+package gp
+
+import (
+	"math/rand"
+
+	"golang.org/x/exp/slices"
+)
+
+type EphemeralMode int
+
+const (
+	// EphemeralOne resamples a single randomly chosen ephemeral
+	EphemeralOne EphemeralMode = iota
+	// EphemeralAll resamples every ephemeral of the tree
+	EphemeralAll
+)
+
+// EphemeralMutator resamples ephemeral constants from their generator, the
+// structure of the tree is left untouched (DEAP's mutEphemeral)
+type EphemeralMutator struct {
+	mode EphemeralMode
+	r    *rand.Rand
+}
+
+func NewEphemeralMutator(mode EphemeralMode, r *rand.Rand) *EphemeralMutator {
+	return &EphemeralMutator{
+		mode: mode,
+		r:    r,
+	}
+}
+
+func (m *EphemeralMutator) Mutate(ind *PrimitiveTree) (*PrimitiveTree, VariationResult) {
+	var indices []int
+	for i, n := range ind.stack {
+		if _, ok := n.(*Ephemeral); ok {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) == 0 {
+		return ind, unchanged(ReasonNoEphemerals)
+	}
+	stack := slices.Clone(ind.stack)
+	if m.mode == EphemeralOne {
+		indices = []int{indices[m.r.Intn(len(indices))]}
+	}
+	for _, i := range indices {
+		stack[i] = stack[i].(*Ephemeral).Sample(m.r)
+	}
+	return NewPrimitiveTree(stack), changed
+}
+
+// NodeReplacementMutator replaces a random node with another primitive or
+// terminal of the same type (DEAP's mutNodeReplacement), an ephemeral can be
+// replaced by a resample of itself
+type NodeReplacementMutator struct {
+	ps *PrimitiveSet
+	r  *rand.Rand
+}
+
+func NewNodeReplacementMutator(ps *PrimitiveSet, r *rand.Rand) *NodeReplacementMutator {
+	return &NodeReplacementMutator{
+		ps: ps,
+		r:  r,
+	}
+}
+
+func (m *NodeReplacementMutator) Mutate(ind *PrimitiveTree) (*PrimitiveTree, VariationResult) {
+	if len(ind.stack) < 2 {
+		return ind, unchanged(ReasonTooSmall)
+	}
+	stack := slices.Clone(ind.stack)
+	index := m.r.Intn(len(stack))
+	node := stack[index]
+	var candidates []Node
+	if node.Arity() == 0 {
+		terms, ephs := m.ps.terminalsFor(node.Ret())
+		for _, t := range terms {
+			if Node(t) != node {
+				candidates = append(candidates, t)
+			}
+		}
+		for _, e := range ephs {
+			candidates = append(candidates, e)
+		}
+	} else {
+		prim := node.(*Primitive)
+		for _, p := range m.ps.Primitives[prim.Ret()] {
+			if p != prim && p.Equals(*prim) {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	choice, ok := weightedChoice(m.ps, candidates, m.r)
+	if !ok {
+		return ind, unchanged(ReasonNoCandidate)
+	}
+	if e, ok := choice.(*Ephemeral); ok {
+		choice = e.Sample(m.r)
+	}
+	stack[index] = choice
+	return NewPrimitiveTree(stack), changed
+}
+
+// InsertMutator wraps a random subtree into a new primitive that takes the
+// subtree's type as one of its arguments, the remaining arguments are
+// generated by expr (DEAP's mutInsert)
+type InsertMutator struct {
+	expr func(*PrimitiveSet, Type) []Node
+	ps   *PrimitiveSet
+	r    *rand.Rand
+}
+
+func NewInsertMutator(ps *PrimitiveSet, expr func(*PrimitiveSet, Type) []Node, r *rand.Rand) *InsertMutator {
+	return &InsertMutator{
+		expr: expr,
+		ps:   ps,
+		r:    r,
+	}
+}
+
+func (m *InsertMutator) Mutate(ind *PrimitiveTree) (*PrimitiveTree, VariationResult) {
+	index := m.r.Intn(len(ind.stack))
+	sliceStart, sliceEnd := ind.SearchSubtree(index)
+	type_ := ind.stack[index].Ret()
+
+	var candidates []*Primitive
+	for _, p := range m.ps.primitivesFor(type_) {
+		if slices.ContainsFunc(p.argTypes, func(argType Type) bool { return IsSubtype(type_, argType) }) {
+			candidates = append(candidates, p)
+		}
+	}
+	prim, ok := weightedChoice(m.ps, candidates, m.r)
+	if !ok {
+		return ind, unchanged(ReasonNoCandidate)
+	}
+	var positions []int
+	for i, argType := range prim.argTypes {
+		if IsSubtype(type_, argType) {
+			positions = append(positions, i)
+		}
+	}
+	position := positions[m.r.Intn(len(positions))]
+
+	newNodes := []Node{prim}
+	for i, argType := range prim.argTypes {
+		if i == position {
+			newNodes = append(newNodes, ind.stack[sliceStart:sliceEnd]...)
+		} else {
+			newNodes = append(newNodes, m.expr(m.ps, argType)...)
+		}
+	}
+	return NewPrimitiveTree(ReplaceInRange(ind.stack, sliceStart, sliceEnd, newNodes...)), changed
+}
+
+// ShrinkMutator replaces a random primitive (except the root) with one of its
+// own arguments of the same type (DEAP's mutShrink)
+type ShrinkMutator struct {
+	r *rand.Rand
+}
+
+func NewShrinkMutator(r *rand.Rand) *ShrinkMutator {
+	return &ShrinkMutator{
+		r: r,
+	}
+}
+
+func (m *ShrinkMutator) Mutate(ind *PrimitiveTree) (*PrimitiveTree, VariationResult) {
+	if len(ind.stack) < 3 || ind.Height() <= 1 {
+		return ind, unchanged(ReasonTooSmall)
+	}
+	var candidates []int
+	for i, n := range ind.stack[1:] {
+		if prim, ok := n.(*Primitive); ok && prim.shrinkable() {
+			candidates = append(candidates, i+1)
+		}
+	}
+	if len(candidates) == 0 {
+		return ind, unchanged(ReasonNoCandidate)
+	}
+	index := candidates[m.r.Intn(len(candidates))]
+	prim := ind.stack[index].(*Primitive)
+	var args []int
+	for i, argType := range prim.argTypes {
+		if IsSubtype(argType, prim.Ret()) {
+			args = append(args, i)
+		}
+	}
+	argIndex := args[m.r.Intn(len(args))]
+
+	childStart, childEnd := index+1, index+1
+	for i := 0; i <= argIndex; i++ {
+		childStart, childEnd = ind.SearchSubtree(childEnd)
+	}
+	sliceStart, sliceEnd := ind.SearchSubtree(index)
+	return NewPrimitiveTree(ReplaceInRange(ind.stack, sliceStart, sliceEnd, ind.stack[childStart:childEnd]...)), changed
+}
+
+// shrinkable tells if one of the arguments can take the place of the primitive
+func (p *Primitive) shrinkable() bool {
+	return slices.ContainsFunc(p.argTypes, func(argType Type) bool { return IsSubtype(argType, p.Ret()) })
+}
@@ -0,0 +1,204 @@
+package gp
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getEphemeralPrimitiveSet() *PrimitiveSet {
+	ps := getPrimitiveSet()
+	ps.AddEphemeralConstant("rand_int", reflect.Int, func(r *rand.Rand) PrimitiveArgs {
+		return r.Intn(1000)
+	})
+	return ps
+}
+
+func countChanged(t *testing.T, before, after *PrimitiveTree) int {
+	assert.Len(t, after.Nodes(), len(before.Nodes()))
+	changed := 0
+	for i := range before.Nodes() {
+		assert.Equal(t, before.Nodes()[i].Name(), after.Nodes()[i].Name())
+		if before.Nodes()[i] != after.Nodes()[i] {
+			changed++
+		}
+	}
+	return changed
+}
+
+func TestEphemeralMutator(t *testing.T) {
+	ps := getEphemeralPrimitiveSet()
+	r := rand.New(rand.NewSource(5))
+	tree, err := ParseTree(`prim1(12, prim2("hello", prim1(45, "hello")))`, ps)
+	assert.NoError(t, err)
+	before := tree.String()
+
+	one, res := NewEphemeralMutator(EphemeralOne, r).Mutate(tree)
+	assert.True(t, res.Changed)
+	assert.Equal(t, 1, countChanged(t, tree, one))
+	assert.NotEqual(t, before, one.String())
+
+	all, _ := NewEphemeralMutator(EphemeralAll, r).Mutate(tree)
+	assert.Equal(t, 2, countChanged(t, tree, all))
+	assert.Equal(t, before, tree.String())
+	mustCompile(t, all)
+}
+
+func TestEphemeralMutatorNoEphemerals(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	tree := NewPrimitiveTree(getValidNodes())
+	mutated, res := NewEphemeralMutator(EphemeralAll, r).Mutate(tree)
+	assert.Equal(t, tree.Nodes(), mutated.Nodes())
+	assert.Equal(t, unchanged(ReasonNoEphemerals), res)
+}
+
+func TestEphemeralMutatorLimiter(t *testing.T) {
+	ps := getEphemeralPrimitiveSet()
+	r := rand.New(rand.NewSource(5))
+	var mutator Mutator = StaticMutatorLimiter(NewEphemeralMutator(EphemeralOne, r).Mutate, MeasureSize, 17)
+	tree := GenerateTree(ps, 2, 3, GenFull, ps.RetType, r)
+	mutated, _ := mutator(tree)
+	assert.Len(t, mutated.Nodes(), len(tree.Nodes()))
+}
+
+func TestNodeReplacementMutator(t *testing.T) {
+	ps := getPrimitiveSet()
+	var prim3 = NewPrimitive("prim3", func(a ...PrimitiveArgs) PrimitiveArgs {
+		return len(a[1].(string)) + a[0].(int)
+	}, []Type{reflect.Int, reflect.String}, reflect.Int)
+	ps.AddPrimitive(prim3)
+	ps.AddTerminal(NewTerminal("term3", reflect.Int, 7))
+	r := rand.New(rand.NewSource(21))
+	mutator := NewNodeReplacementMutator(ps, r)
+
+	tree := GenerateTree(ps, 2, 4, GenFull, ps.RetType, r)
+	for i := 0; i < 20; i++ {
+		mutated, _ := mutator.Mutate(tree)
+		assert.Len(t, mutated.Nodes(), len(tree.Nodes()))
+		for j, n := range mutated.Nodes() {
+			orig := tree.Nodes()[j]
+			assert.Equal(t, orig.Ret(), n.Ret())
+			assert.Equal(t, orig.Arity(), n.Arity())
+			if p, ok := n.(*Primitive); ok {
+				assert.True(t, p.Equals(*orig.(*Primitive)))
+			}
+		}
+		mustCompile(t, mutated)
+		tree = mutated
+	}
+
+	// none of the nodes has an alternative
+	single := NewPrimitiveTree([]Node{prim2, term2, term1})
+	mutator = NewNodeReplacementMutator(getPrimitiveSet(), r)
+	for i := 0; i < 10; i++ {
+		mutated, res := mutator.Mutate(single)
+		assert.Equal(t, single.Nodes(), mutated.Nodes())
+		assert.Equal(t, unchanged(ReasonNoCandidate), res)
+	}
+}
+
+func TestNodeReplacementMutatorAlwaysChanges(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	r := rand.New(rand.NewSource(13))
+	mutator := NewNodeReplacementMutator(ps, r)
+	tree, _ := ParseTree("prog2(turn_left, if_food_ahead(turn_right, move_forward))", ps)
+	for i := 0; i < 20; i++ {
+		mutated, res := mutator.Mutate(tree)
+		assert.True(t, res.Changed)
+		diff := 0
+		for j, n := range mutated.Nodes() {
+			if n != tree.Nodes()[j] {
+				diff++
+			}
+		}
+		assert.Equal(t, 1, diff)
+		tree = mutated
+	}
+}
+
+func TestNodeReplacementMutatorReproducible(t *testing.T) {
+	ps := getEphemeralPrimitiveSet()
+	tree := GenerateTree(ps, 3, 4, GenFull, ps.RetType, rand.New(rand.NewSource(3)))
+	mutate := func() string {
+		r := rand.New(rand.NewSource(99))
+		mutator := NewNodeReplacementMutator(ps, r)
+		res := tree
+		for i := 0; i < 10; i++ {
+			res, _ = mutator.Mutate(res)
+		}
+		return res.String()
+	}
+	assert.Equal(t, mutate(), mutate())
+}
+
+func TestNodeReplacementMutatorWeights(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	ps.SetWeight("prog2", 0)
+	ps.SetWeight("turn_left", 0)
+	ps.SetWeight("turn_right", 0)
+	r := rand.New(rand.NewSource(19))
+	tree, _ := ParseTree("prog2(turn_left, if_food_ahead(turn_right, turn_left))", ps)
+	mutator := NewNodeReplacementMutator(ps, r)
+	for i := 0; i < 20; i++ {
+		tree, _ = mutator.Mutate(tree)
+	}
+	assert.Equal(t, "if_food_ahead(move_forward, if_food_ahead(move_forward, move_forward))", tree.String())
+}
+
+func TestInsertMutator(t *testing.T) {
+	ps := getPrimitiveSet()
+	r := rand.New(rand.NewSource(8))
+	mutator := NewInsertMutator(ps, func(ps *PrimitiveSet, type_ Type) []Node {
+		return GenerateTree(ps, 0, 1, GenFull, type_, r).Nodes()
+	}, r)
+	tree := NewPrimitiveTree(getValidNodes())
+	for i := 0; i < 10; i++ {
+		mutated, _ := mutator.Mutate(tree)
+		// a new primitive and a terminal for its other argument
+		assert.Len(t, mutated.Nodes(), len(tree.Nodes())+2)
+		assert.NoError(t, checkNodes(mutated.Nodes(), ps.RetType))
+		mustCompile(t, mutated)
+		tree = mutated
+	}
+}
+
+func TestInsertMutatorNoCandidate(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	ps.Primitives = map[Type][]*Primitive{}
+	r := rand.New(rand.NewSource(8))
+	mutator := NewInsertMutator(ps, func(ps *PrimitiveSet, type_ Type) []Node {
+		return GenerateTree(ps, 0, 1, GenFull, type_, r).Nodes()
+	}, r)
+	tree, _ := ParseTree("turn_left", getFuncPrimitiveSet())
+	mutated, res := mutator.Mutate(tree)
+	assert.Equal(t, tree.Nodes(), mutated.Nodes())
+	assert.Equal(t, unchanged(ReasonNoCandidate), res)
+}
+
+func TestShrinkMutator(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	r := rand.New(rand.NewSource(8))
+	mutator := NewShrinkMutator(r)
+	tree, err := ParseTree("prog2(prog3(turn_left, if_food_ahead(move_forward, turn_right), turn_left), move_forward)", ps)
+	assert.NoError(t, err)
+	for len(tree.Nodes()) > 3 {
+		mutated, _ := mutator.Mutate(tree)
+		assert.Less(t, len(mutated.Nodes()), len(tree.Nodes()))
+		assert.Equal(t, tree.Nodes()[0], mutated.Nodes()[0])
+		assert.NoError(t, checkNodes(mutated.Nodes(), ps.RetType))
+		tree = mutated
+	}
+	mutated, res := mutator.Mutate(tree)
+	assert.Equal(t, tree.Nodes(), mutated.Nodes())
+	assert.Equal(t, unchanged(ReasonTooSmall), res)
+}
+
+func TestShrinkMutatorTyped(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	// prim2 is the only primitive with its return type among its arguments
+	tree := NewPrimitiveTree([]Node{prim1, term1, prim2, prim2, term2, term1, term1})
+	mutated, _ := NewShrinkMutator(r).Mutate(tree)
+	assert.Equal(t, []Node{prim1, term1, prim2, term2, term1}, mutated.Nodes())
+}
@@ -0,0 +1,312 @@
+package gp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse error at position %d: %s", e.Pos, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenLiteral
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, &ParseError{i, "unterminated string literal"}
+			}
+			tokens = append(tokens, token{tokenLiteral, expr[i : end+1], i})
+			i = end + 1
+		case c == '-' || c == '+' || c == '.' || unicode.IsDigit(c):
+			end := i + 1
+			for end < len(expr) && strings.ContainsRune("0123456789.eE+-", rune(expr[end])) {
+				end++
+			}
+			tokens = append(tokens, token{tokenLiteral, expr[i:end], i})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(expr) && (expr[end] == '_' || unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{tokenIdent, expr[i:end], i})
+			i = end
+		default:
+			return nil, &ParseError{i, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(expr)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	ps     *PrimitiveSet
+	nodes  []Node
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(kind tokenKind, what string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		return &ParseError{tok.pos, fmt.Sprintf("expected %s, got %q", what, tok.text)}
+	}
+	return nil
+}
+
+func (p *parser) parseExpr(type_ Type) error {
+	tok := p.next()
+	switch tok.kind {
+	case tokenIdent:
+		if p.peek().kind == tokenLParen {
+			return p.parsePrimitive(tok, type_)
+		}
+		return p.parseTerminal(tok, type_)
+	case tokenLiteral:
+		return p.parseTerminal(tok, type_)
+	case tokenEOF:
+		return &ParseError{tok.pos, "unexpected end of expression"}
+	default:
+		return &ParseError{tok.pos, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+}
+
+func (p *parser) parsePrimitive(name token, type_ Type) error {
+	prim := p.ps.findPrimitive(name.text, type_)
+	if prim == nil {
+		if other := p.ps.findPrimitive(name.text, reflect.Invalid); other != nil {
+			return &ParseError{name.pos, fmt.Sprintf("primitive %s returns %v, expected %v", other.Name(), other.Ret(), type_)}
+		}
+		return &ParseError{name.pos, fmt.Sprintf("unknown primitive %s", name.text)}
+	}
+	p.nodes = append(p.nodes, prim)
+	p.next() // opening paren, checked by parseExpr
+	for i, argType := range prim.argTypes {
+		if i > 0 {
+			if tok := p.peek(); tok.kind == tokenRParen {
+				return &ParseError{tok.pos, fmt.Sprintf("primitive %s expects %d arguments, got %d", prim.Name(), prim.Arity(), i)}
+			}
+			if err := p.expect(tokenComma, "','"); err != nil {
+				return err
+			}
+		}
+		if err := p.parseExpr(argType); err != nil {
+			return err
+		}
+	}
+	if tok := p.peek(); tok.kind == tokenComma {
+		return &ParseError{tok.pos, fmt.Sprintf("primitive %s expects %d arguments, got more", prim.Name(), prim.Arity())}
+	}
+	return p.expect(tokenRParen, "')'")
+}
+
+func (p *parser) parseTerminal(tok token, type_ Type) error {
+	term := p.ps.findTerminal(tok.text, type_)
+	if _, ephs := p.ps.terminalsFor(type_); term == nil && len(ephs) > 0 {
+		// the printed form of an ephemeral is its value, there is no way to
+		// tell which ephemeral of the same type created it so we use the first
+		value, err := parseLiteral(tok.text, ephs[0].Ret())
+		if err != nil {
+			return &ParseError{tok.pos, err.Error()}
+		}
+		term = ephs[0].WithValue(value)
+	}
+	if term == nil {
+		if other := p.ps.findTerminal(tok.text, reflect.Invalid); other != nil {
+			return &ParseError{tok.pos, fmt.Sprintf("terminal %s is %v, expected %v", tok.text, other.Ret(), type_)}
+		}
+		if prim := p.ps.findPrimitive(tok.text, reflect.Invalid); prim != nil {
+			return &ParseError{tok.pos, fmt.Sprintf("primitive %s used without arguments", tok.text)}
+		}
+		return &ParseError{tok.pos, fmt.Sprintf("unknown terminal %s", tok.text)}
+	}
+	p.nodes = append(p.nodes, term)
+	return nil
+}
+
+// findPrimitive looks a primitive up by name among the ones that can be used
+// where type_ is expected, reflect.Invalid matches any type
+func (ps *PrimitiveSet) findPrimitive(name string, type_ Type) *Primitive {
+	var prims []*Primitive
+	if type_ == reflect.Invalid {
+		for _, ps := range ps.Primitives {
+			prims = append(prims, ps...)
+		}
+	} else {
+		prims = ps.primitivesFor(type_)
+	}
+	for _, prim := range prims {
+		if prim.Name() == name {
+			return prim
+		}
+	}
+	return nil
+}
+
+// findTerminal looks a terminal up by name or by its printed value,
+// reflect.Invalid matches any type
+func (ps *PrimitiveSet) findTerminal(text string, type_ Type) Node {
+	var terms []*Terminal
+	if type_ == reflect.Invalid {
+		for _, ts := range ps.Terminals {
+			terms = append(terms, ts...)
+		}
+	} else {
+		terms, _ = ps.terminalsFor(type_)
+	}
+	for _, term := range terms {
+		if term.Name() == text || term.Str(nil) == text {
+			return term
+		}
+	}
+	return nil
+}
+
+// findEphemeral looks an ephemeral up by name among the ones that can be used
+// where type_ is expected, reflect.Invalid matches any type
+func (ps *PrimitiveSet) findEphemeral(name string, type_ Type) *Ephemeral {
+	var ephs []*Ephemeral
+	if type_ == reflect.Invalid {
+		for _, es := range ps.Ephemerals {
+			ephs = append(ephs, es...)
+		}
+	} else {
+		_, ephs = ps.terminalsFor(type_)
+	}
+	for _, eph := range ephs {
+		if eph.Name() == name {
+			return eph
+		}
+	}
+	return nil
+}
+
+var literalTypes = map[reflect.Kind]reflect.Type{
+	reflect.Int:     TypeFor[int](),
+	reflect.Int8:    TypeFor[int8](),
+	reflect.Int16:   TypeFor[int16](),
+	reflect.Int32:   TypeFor[int32](),
+	reflect.Int64:   TypeFor[int64](),
+	reflect.Uint:    TypeFor[uint](),
+	reflect.Uint8:   TypeFor[uint8](),
+	reflect.Uint16:  TypeFor[uint16](),
+	reflect.Uint32:  TypeFor[uint32](),
+	reflect.Uint64:  TypeFor[uint64](),
+	reflect.Float32: TypeFor[float32](),
+	reflect.Float64: TypeFor[float64](),
+	reflect.Bool:    TypeFor[bool](),
+	reflect.String:  TypeFor[string](),
+}
+
+// literalType returns the type parseLiteral produces for type_, only numbers,
+// booleans and strings (including named types of them) have literals
+func literalType(type_ Type) (reflect.Type, bool) {
+	if t, ok := type_.(reflect.Type); ok {
+		_, ok = literalTypes[t.Kind()]
+		return t, ok
+	}
+	t, ok := literalTypes[kindOf(type_)]
+	return t, ok
+}
+
+// parseLiteral converts the printed value of an ephemeral back to a value of the given type
+func parseLiteral(text string, type_ Type) (PrimitiveArgs, error) {
+	t, ok := literalType(type_)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("cannot parse literal %s of kind %v", text, type_))
+	}
+	var value any
+	var err error
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err = strconv.ParseInt(text, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err = strconv.ParseUint(text, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		value, err = strconv.ParseFloat(text, t.Bits())
+	case reflect.Bool:
+		value, err = strconv.ParseBool(text)
+	case reflect.String:
+		value, err = strconv.Unquote(text)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid %v literal %s", type_, text))
+	}
+	// also covers named types like type celsius float64
+	return reflect.ValueOf(value).Convert(t).Interface(), nil
+}
+
+// ParseTree is the inverse of PrimitiveTree.String, nodes are resolved by
+// name against the given primitive set and the root has to return ps.RetType
+func ParseTree(expr string, ps *PrimitiveSet) (*PrimitiveTree, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, ps: ps}
+	if err := p.parseExpr(ps.RetType); err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &ParseError{tok.pos, fmt.Sprintf("unexpected %q after expression", tok.text)}
+	}
+	return NewPrimitiveTree(p.nodes), nil
+}
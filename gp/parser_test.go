@@ -0,0 +1,153 @@
+package gp
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getFuncPrimitiveSet() *PrimitiveSet {
+	var noop PrimitiveFunc = func(_ ...PrimitiveArgs) PrimitiveArgs { return nil }
+	ps := NewPrimitiveSet([]Type{}, reflect.Func)
+	ps.AddPrimitive(NewPrimitive("prog3", noop, []Type{reflect.Func, reflect.Func, reflect.Func}, reflect.Func))
+	ps.AddPrimitive(NewPrimitive("prog2", noop, []Type{reflect.Func, reflect.Func}, reflect.Func))
+	ps.AddPrimitive(NewPrimitive("if_food_ahead", noop, []Type{reflect.Func, reflect.Func}, reflect.Func))
+	ps.AddTerminal(NewTerminal("move_forward", reflect.Func, noop))
+	ps.AddTerminal(NewTerminal("turn_left", reflect.Func, noop))
+	ps.AddTerminal(NewTerminal("turn_right", reflect.Func, noop))
+	return ps
+}
+
+func TestParseTree(t *testing.T) {
+	ps := getPrimitiveSet()
+	tree, err := ParseTree(`prim1(4, prim2("hello", 4))`, ps)
+	assert.NoError(t, err)
+	assert.Equal(t, getValidNodes(), tree.Nodes())
+	assert.Equal(t, 5*4*4, mustCompile(t, tree).(int))
+}
+
+func TestParseTreeRoundTrip(t *testing.T) {
+	ps := getPrimitiveSet()
+	r := rand.New(rand.NewSource(31))
+	for i := 0; i < 20; i++ {
+		tree := GenerateTree(ps, 1, 4, GenGrow, ps.RetType, r)
+		parsed, err := ParseTree(tree.String(), ps)
+		assert.NoError(t, err)
+		assert.Equal(t, tree.Nodes(), parsed.Nodes())
+	}
+}
+
+func TestParseTreeArguments(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{reflect.Int, reflect.String}, reflect.Int)
+	ps.AddPrimitive(prim1)
+	tree, err := ParseTree(`prim1(__ARG__0, __ARG__1)`, ps)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, mustCompile(t, tree, 2, "abcde"))
+}
+
+func TestParseTreeBareTerminals(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	expr := "prog3(prog3(turn_right, move_forward, turn_left), if_food_ahead(move_forward, prog2(turn_left, turn_left)), move_forward)"
+	tree, err := ParseTree(expr, ps)
+	assert.NoError(t, err)
+	assert.Len(t, tree.Nodes(), 11)
+	assert.Equal(t, expr, tree.String())
+
+	tree, err = ParseTree("turn_right", ps)
+	assert.NoError(t, err)
+	assert.Equal(t, "turn_right", tree.String())
+}
+
+func TestParseTreeErrors(t *testing.T) {
+	ps := getPrimitiveSet()
+	for _, tc := range []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{`prim3(4, "hello")`, 0, "unknown primitive prim3"},
+		{`prim1(4, "bye")`, 9, `unknown terminal "bye"`},
+		{`prim1(4)`, 7, "primitive prim1 expects 2 arguments, got 1"},
+		{`prim1(4, "hello", 4)`, 16, "primitive prim1 expects 2 arguments, got more"},
+		{`prim1("hello", 4)`, 6, "terminal \"hello\" is string, expected int"},
+		{`prim2("hello", 4)`, 0, "primitive prim2 returns string, expected int"},
+		{`prim1(4, prim2("hello", 4)`, 26, "expected ')', got \"\""},
+		{`prim1(4, prim2("hello, 4))`, 15, "unterminated string literal"},
+		{`prim1(4, prim2) `, 9, "primitive prim2 used without arguments"},
+		{`prim1(4, "hello") 4`, 18, `unexpected "4" after expression`},
+		{`prim1(4; "hello")`, 7, "unexpected character ';'"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := ParseTree(tc.expr, ps)
+			assert.Equal(t, &ParseError{Pos: tc.pos, Msg: tc.msg}, err)
+		})
+	}
+}
+
+func TestParseTreeEphemeral(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	ps.AddPrimitive(prim1)
+	ps.AddEphemeralConstant("rand_int", reflect.Int, func(r *rand.Rand) PrimitiveArgs { return r.Intn(10) })
+	ps.AddEphemeralConstant("rand_str", reflect.String, func(r *rand.Rand) PrimitiveArgs { return "abc" })
+
+	tree, err := ParseTree(`prim1(7, "xy")`, ps)
+	assert.NoError(t, err)
+	assert.Equal(t, 14, mustCompile(t, tree))
+	assert.Equal(t, `prim1(7, "xy")`, tree.String())
+	assert.Equal(t, "rand_int", tree.Nodes()[1].Name())
+	assert.Equal(t, "rand_str", tree.Nodes()[2].Name())
+
+	_, err = ParseTree(`prim1(7.5, "xy")`, ps)
+	assert.Equal(t, &ParseError{Pos: 6, Msg: "invalid int literal 7.5"}, err)
+}
+
+func TestParseTreeOverloadedPrimitive(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.String)
+	ps.AddPrimitive(prim1)
+	ps.AddPrimitive(prim2)
+	ps.AddTerminal(term1)
+	ps.AddTerminal(term2)
+	concat := NewPrimitive("prim1", func(a ...PrimitiveArgs) PrimitiveArgs {
+		return a[0].(string) + a[1].(string)
+	}, []Type{reflect.String, reflect.String}, reflect.String)
+	ps.AddPrimitive(concat)
+	for i := 0; i < 10; i++ {
+		tree, err := ParseTree(`prim2(prim1("hello", "hello"), prim1(4, "hello"))`, ps)
+		assert.NoError(t, err)
+		assert.Same(t, concat, tree.Nodes()[1])
+		assert.Same(t, prim1, tree.Nodes()[4])
+	}
+}
+
+func TestParseTreeLiterals(t *testing.T) {
+	type celsius float32
+	ps := NewPrimitiveSet([]Type{}, reflect.String)
+	ps.AddPrimitive(NewPrimitive("show", func(a ...PrimitiveArgs) PrimitiveArgs {
+		return fmt.Sprint(a[0], a[1], a[2])
+	}, []Type{reflect.String, reflect.Uint8, TypeFor[celsius]()}, reflect.String))
+	ps.AddEphemeralConstant("rand_str", reflect.String, func(r *rand.Rand) PrimitiveArgs { return `say "hi"\` })
+	ps.AddEphemeralConstant("rand_byte", reflect.Uint8, func(r *rand.Rand) PrimitiveArgs { return uint8(r.Intn(256)) })
+	ps.AddEphemeral(NewTypedEphemeral("rand_temp", func(r *rand.Rand) celsius { return celsius(r.Intn(40)) }))
+
+	tree := GenerateTree(ps, 1, 2, GenFull, ps.RetType, rand.New(rand.NewSource(5)))
+	assert.Contains(t, tree.String(), `"say \"hi\"\\"`)
+	parsed, err := ParseTree(tree.String(), ps)
+	assert.NoError(t, err)
+	assert.Equal(t, tree.String(), parsed.String())
+	assert.Equal(t, mustCompile(t, tree), mustCompile(t, parsed))
+
+	_, err = ParseTree(`show("a", 256, 1)`, ps)
+	assert.Equal(t, &ParseError{Pos: 10, Msg: "invalid uint8 literal 256"}, err)
+}
+
+func TestAddEphemeralUnparsable(t *testing.T) {
+	type point struct{ x, y int }
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	assert.Panics(t, func() {
+		ps.AddEphemeral(NewTypedEphemeral("rand_point", func(r *rand.Rand) point { return point{} }))
+	})
+	assert.Empty(t, ps.Ephemerals)
+}
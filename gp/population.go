@@ -0,0 +1,34 @@
+package gp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// InitRampedHalfAndHalf creates a population with Koza's ramped half-and-half
+// method. The individuals are spread evenly over the heights min..max
+// (inclusive), for every height half of the trees are built with GenFull and
+// the other half with GenGrow. Every individual gets an invalid fitness with
+// the given weights.
+func InitRampedHalfAndHalf(size int, ps *PrimitiveSet, min int, max int, weights []float32, factory IndividualFactory, r *rand.Rand) ([]Individual, error) {
+	if min < 0 || max < min {
+		return nil, errors.New(fmt.Sprintf("invalid height range %d..%d", min, max))
+	}
+	heights := max - min + 1
+	inds := make([]Individual, 0, size)
+	for i := 0; i < size; i++ {
+		fit, err := NewFitness(append([]float32{}, weights...))
+		if err != nil {
+			return nil, err
+		}
+		height := min + i%heights
+		condition := GenFull
+		if (i/heights)%2 == 1 {
+			condition = GenGrow
+		}
+		tree := generateTree(ps, height, min, max, condition, ps.RetType, r)
+		inds = append(inds, factory(tree, fit))
+	}
+	return inds, nil
+}
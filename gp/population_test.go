@@ -0,0 +1,51 @@
+package gp
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitRampedHalfAndHalf(t *testing.T) {
+	ps := getPrimitiveSet()
+	r := rand.New(rand.NewSource(16))
+	inds, err := InitRampedHalfAndHalf(40, ps, 1, 4, []float32{1}, individualFactory, r)
+	assert.NoError(t, err)
+	assert.Len(t, inds, 40)
+
+	fullPerHeight := map[int]int{}
+	for i, ind := range inds {
+		assert.False(t, ind.Fitness().Valid())
+		assert.Equal(t, []float32{1}, ind.Fitness().GetWeights())
+		assert.NoError(t, checkNodes(ind.Tree().Nodes(), ps.RetType))
+		height := 1 + i%4
+		assert.LessOrEqual(t, ind.Tree().Height(), height)
+		if ind.Tree().Height() == height && isFull(ind.Tree()) {
+			fullPerHeight[height]++
+		}
+	}
+	// the full half of every height
+	for height := 1; height <= 4; height++ {
+		assert.GreaterOrEqual(t, fullPerHeight[height], 5)
+	}
+
+	_, err = InitRampedHalfAndHalf(10, ps, 3, 2, []float32{1}, individualFactory, r)
+	assert.EqualError(t, err, "invalid height range 3..2")
+}
+
+// isFull tells if all the leaves of the tree are at its height
+func isFull(tree *PrimitiveTree) bool {
+	height, depths := tree.Height(), []int{0}
+	for _, n := range tree.Nodes() {
+		depth := depths[len(depths)-1]
+		depths = depths[:len(depths)-1]
+		if n.Arity() == 0 && depth != height {
+			return false
+		}
+		for i := 0; i < n.Arity(); i++ {
+			depths = append(depths, depth+1)
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+// Package primitives provides ready-made groups of primitives for the common
+// arithmetic, logic and comparison operators. The operators are protected, a
+// division by zero or the logarithm of a negative number does not panic and
+// float64 results are always finite: NaN becomes 0 and infinities are clamped
+// to ±math.MaxFloat64.
+//
+// Primitives existing for several kinds have the kind in their name, e.g.
+// add_float64 and add_int, so groups can be mixed in a single PrimitiveSet.
+package primitives
+
+import (
+	"fmt"
+	"main/gp"
+	"math"
+)
+
+// maxExp keeps math.Exp finite
+const maxExp = 700
+
+type number interface {
+	~int | ~float64
+}
+
+// Finite returns x with NaN mapped to 0 and infinities clamped to the largest
+// finite float64, integers are returned unchanged
+func Finite[T number](x T) T {
+	f := float64(x)
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case math.IsInf(f, 0):
+		// a variable as the constant does not fit the integer instances of T
+		limit := math.MaxFloat64
+		if f < 0 {
+			limit = -limit
+		}
+		return T(limit)
+	}
+	return x
+}
+
+// ProtectedDiv returns a/b, or 1 if b is zero as in DEAP
+func ProtectedDiv(a, b float64) float64 {
+	if b == 0 {
+		return 1
+	}
+	return Finite(a / b)
+}
+
+// ProtectedIntDiv returns a/b, or 1 if b is zero
+func ProtectedIntDiv(a, b int) int {
+	if b == 0 {
+		return 1
+	}
+	return a / b
+}
+
+// ProtectedMod returns a%b, or 0 if b is zero
+func ProtectedMod(a, b int) int {
+	if b == 0 {
+		return 0
+	}
+	return a % b
+}
+
+// ProtectedExp returns e**x with x capped so the result stays finite
+func ProtectedExp(x float64) float64 {
+	return Finite(math.Exp(math.Min(x, maxExp)))
+}
+
+// ProtectedLog returns the natural logarithm of |x|, or 0 if x is zero
+func ProtectedLog(x float64) float64 {
+	if x == 0 {
+		return 0
+	}
+	return Finite(math.Log(math.Abs(x)))
+}
+
+// ProtectedSqrt returns the square root of |x|
+func ProtectedSqrt(x float64) float64 {
+	return Finite(math.Sqrt(math.Abs(x)))
+}
+
+// ProtectedSin returns the sine of x, or 0 for infinities
+func ProtectedSin(x float64) float64 {
+	return Finite(math.Sin(x))
+}
+
+// ProtectedCos returns the cosine of x, or 0 for infinities
+func ProtectedCos(x float64) float64 {
+	return Finite(math.Cos(x))
+}
+
+// FloatArithmetic returns add, sub, mul, div (protected) and neg on float64
+func FloatArithmetic() []*gp.Primitive {
+	return append(arithmetic[float64](), gp.NewPrimitive2("div_float64", ProtectedDiv))
+}
+
+// IntArithmetic returns add, sub, mul, div and mod (both protected) and neg on int
+func IntArithmetic() []*gp.Primitive {
+	return append(arithmetic[int](),
+		gp.NewPrimitive2("div_int", ProtectedIntDiv),
+		gp.NewPrimitive2("mod_int", ProtectedMod),
+	)
+}
+
+func arithmetic[T number]() []*gp.Primitive {
+	return []*gp.Primitive{
+		gp.NewPrimitive2(kindName[T]("add"), func(a, b T) T { return Finite(a + b) }),
+		gp.NewPrimitive2(kindName[T]("sub"), func(a, b T) T { return Finite(a - b) }),
+		gp.NewPrimitive2(kindName[T]("mul"), func(a, b T) T { return Finite(a * b) }),
+		gp.NewPrimitive1(kindName[T]("neg"), func(a T) T { return Finite(-a) }),
+	}
+}
+
+// Trigonometry returns sin and cos (both protected) on float64
+func Trigonometry() []*gp.Primitive {
+	return []*gp.Primitive{
+		gp.NewPrimitive1("sin", ProtectedSin),
+		gp.NewPrimitive1("cos", ProtectedCos),
+	}
+}
+
+// ExpLog returns exp, log and sqrt on float64 with protected domains
+func ExpLog() []*gp.Primitive {
+	return []*gp.Primitive{
+		gp.NewPrimitive1("exp", ProtectedExp),
+		gp.NewPrimitive1("log", ProtectedLog),
+		gp.NewPrimitive1("sqrt", ProtectedSqrt),
+	}
+}
+
+// Logic returns and, or, xor and not on bool
+func Logic() []*gp.Primitive {
+	return []*gp.Primitive{
+		gp.NewPrimitive2("and", func(a, b bool) bool { return a && b }),
+		gp.NewPrimitive2("or", func(a, b bool) bool { return a || b }),
+		gp.NewPrimitive2("xor", func(a, b bool) bool { return a != b }),
+		gp.NewPrimitive1("not", func(a bool) bool { return !a }),
+	}
+}
+
+// FloatComparison returns lt, gt and eq on float64
+func FloatComparison() []*gp.Primitive {
+	return comparison[float64]()
+}
+
+// IntComparison returns lt, gt and eq on int
+func IntComparison() []*gp.Primitive {
+	return comparison[int]()
+}
+
+func comparison[T number]() []*gp.Primitive {
+	return []*gp.Primitive{
+		gp.NewPrimitive2(kindName[T]("lt"), func(a, b T) bool { return a < b }),
+		gp.NewPrimitive2(kindName[T]("gt"), func(a, b T) bool { return a > b }),
+		gp.NewPrimitive2(kindName[T]("eq"), func(a, b T) bool { return a == b }),
+	}
+}
+
+// IfThenElse returns the if_<kind> primitive choosing between its second and
+// third argument of type T depending on its first boolean argument
+func IfThenElse[T any]() *gp.Primitive {
+	return gp.NewPrimitive3(kindName[T]("if"), func(cond bool, a, b T) T {
+		if cond {
+			return a
+		}
+		return b
+	})
+}
+
+// AddGroups adds all the primitives of the groups to the primitive set
+func AddGroups(ps *gp.PrimitiveSet, groups ...[]*gp.Primitive) {
+	for _, group := range groups {
+		for _, p := range group {
+			ps.AddPrimitive(p)
+		}
+	}
+}
+
+func kindName[T any](name string) string {
+	return fmt.Sprintf("%s_%s", name, gp.TypeFor[T]().Kind())
+}
@@ -0,0 +1,98 @@
+package primitives
+
+import (
+	"main/gp"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtected(t *testing.T) {
+	assert.Equal(t, 1.0, ProtectedDiv(3, 0))
+	assert.Equal(t, 1.5, ProtectedDiv(3, 2))
+	assert.Equal(t, 1, ProtectedIntDiv(3, 0))
+	assert.Equal(t, 0, ProtectedMod(3, 0))
+	assert.Equal(t, 1, ProtectedMod(7, 3))
+	assert.False(t, math.IsInf(ProtectedExp(1e6), 0))
+	assert.Equal(t, 0.0, ProtectedExp(math.NaN()))
+	assert.Equal(t, 0.0, ProtectedLog(math.NaN()))
+	assert.Equal(t, 0.0, ProtectedSqrt(math.NaN()))
+	assert.Equal(t, 0.0, ProtectedLog(0))
+	assert.Equal(t, math.Log(2), ProtectedLog(-2))
+	assert.Equal(t, 3.0, ProtectedSqrt(-9))
+	assert.Equal(t, math.MaxFloat64, ProtectedSqrt(math.Inf(-1)))
+	assert.Equal(t, 0.0, ProtectedSin(math.Inf(1)))
+	assert.Equal(t, 0.0, ProtectedCos(math.Inf(-1)))
+	assert.Equal(t, -math.MaxFloat64, ProtectedDiv(-math.MaxFloat64, 0.5))
+	assert.Equal(t, 7, Finite(7))
+}
+
+func TestFloatArithmeticFinite(t *testing.T) {
+	inf := math.Inf(1)
+	for _, tc := range []struct {
+		name string
+		args []gp.PrimitiveArgs
+		want float64
+	}{
+		{"add_float64", []gp.PrimitiveArgs{math.MaxFloat64, math.MaxFloat64}, math.MaxFloat64},
+		{"sub_float64", []gp.PrimitiveArgs{inf, inf}, 0},
+		{"mul_float64", []gp.PrimitiveArgs{0.0, inf}, 0},
+		{"neg_float64", []gp.PrimitiveArgs{inf}, -math.MaxFloat64},
+		{"div_float64", []gp.PrimitiveArgs{inf, inf}, 0},
+	} {
+		for _, p := range FloatArithmetic() {
+			if p.Name() == tc.name {
+				res, err := p.Eval(tc.args)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, res, tc.name)
+			}
+		}
+	}
+}
+
+func names(prims []*gp.Primitive) []string {
+	var res []string
+	for _, p := range prims {
+		res = append(res, p.Name())
+	}
+	return res
+}
+
+func TestGroups(t *testing.T) {
+	assert.Equal(t, []string{"add_float64", "sub_float64", "mul_float64", "neg_float64", "div_float64"}, names(FloatArithmetic()))
+	assert.Equal(t, []string{"add_int", "sub_int", "mul_int", "neg_int", "div_int", "mod_int"}, names(IntArithmetic()))
+	assert.Equal(t, []string{"lt_int", "gt_int", "eq_int"}, names(IntComparison()))
+	assert.Equal(t, "if_float64", IfThenElse[float64]().Name())
+	assert.Equal(t, reflect.Bool, FloatComparison()[0].Ret())
+
+	res, err := IfThenElse[string]().Eval([]gp.PrimitiveArgs{false, "a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", res)
+	res, err = IntArithmetic()[4].Eval([]gp.PrimitiveArgs{5, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res)
+}
+
+func TestAddGroups(t *testing.T) {
+	ps := gp.NewPrimitiveSet([]gp.Type{reflect.Float64}, reflect.Float64)
+	AddGroups(ps, FloatArithmetic(), Trigonometry(), ExpLog(), FloatComparison(), Logic(), []*gp.Primitive{IfThenElse[float64]()})
+	ps.AddEphemeralConstant("rand_float", reflect.Float64, func(r *rand.Rand) gp.PrimitiveArgs { return r.NormFloat64() })
+	_, err := ps.Validate()
+	assert.NoError(t, err)
+	assert.Len(t, ps.Primitives[reflect.Float64], 11)
+	assert.Len(t, ps.Primitives[reflect.Bool], 7)
+
+	r := rand.New(rand.NewSource(21))
+	for i := 0; i < 100; i++ {
+		tree := gp.GenerateTree(ps, 2, 6, gp.GenGrow, ps.RetType, r)
+		for _, x := range []float64{0, -1, 2.5} {
+			res, err := tree.Compile(x)
+			assert.NoError(t, err)
+			assert.False(t, math.IsNaN(res.(float64)), tree.String())
+			assert.False(t, math.IsInf(res.(float64), 0), tree.String())
+		}
+	}
+}
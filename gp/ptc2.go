@@ -0,0 +1,133 @@
+package gp
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// SizeDistribution draws the requested number of nodes of a tree
+type SizeDistribution func(*rand.Rand) int
+
+// UniformSize draws sizes uniformly from min..max (inclusive)
+func UniformSize(min int, max int) SizeDistribution {
+	return func(r *rand.Rand) int {
+		return min + r.Intn(max-min+1)
+	}
+}
+
+// PTC2 is Luke's Probabilistic Tree Creation 2. It grows a tree from its root
+// by expanding randomly chosen open argument slots with primitives until the
+// tree reaches the size drawn from Sizes, the remaining slots are filled with
+// terminals. Slots at MaxDepth, or of a type without primitives, are not
+// expanded further so the size is a target and can be missed. A slot of a
+// type without terminals is closed with the primitives completing it the
+// fastest, which can grow the tree past MaxDepth. Nodes are chosen with the
+// weights of the primitive set.
+type PTC2 struct {
+	ps       *PrimitiveSet
+	Sizes    SizeDistribution
+	MaxDepth int
+	r        *rand.Rand
+}
+
+func NewPTC2(ps *PrimitiveSet, sizes SizeDistribution, maxDepth int, r *rand.Rand) *PTC2 {
+	return &PTC2{
+		ps:       ps,
+		Sizes:    sizes,
+		MaxDepth: maxDepth,
+		r:        r,
+	}
+}
+
+type ptcNode struct {
+	node     Node
+	type_    Type
+	depth    int
+	children []*ptcNode
+}
+
+// Generate builds the nodes of a tree of the given type, it can be used as
+// the expression generator of the mutators
+func (g *PTC2) Generate(ps *PrimitiveSet, type_ Type) []Node {
+	r := g.r
+	report := ps.report(type_)
+	size := g.Sizes(r)
+	root := &ptcNode{type_: type_}
+	count := 0
+	slots := []*ptcNode{root}
+	if size > 1 && len(g.expansions(ps, report, root)) > 0 {
+		slots = append([]*ptcNode{}, g.expand(ps, root, g.expansions(ps, report, root), r)...)
+		count++
+	}
+	for count+len(slots) < size {
+		var open []int
+		for i, s := range slots {
+			if len(g.expansions(ps, report, s)) > 0 {
+				open = append(open, i)
+			}
+		}
+		if len(open) == 0 {
+			break
+		}
+		i := open[r.Intn(len(open))]
+		slot := slots[i]
+		slots = append(slots[:i], slots[i+1:]...)
+		slots = append(slots, g.expand(ps, slot, g.expansions(ps, report, slot), r)...)
+		count++
+	}
+	for _, s := range slots {
+		g.close(ps, report, s, r)
+	}
+
+	var nodes []Node
+	stack := []*ptcNode{root}
+	for len(stack) != 0 {
+		var n *ptcNode
+		stack, n = Pop(stack)
+		nodes = append(nodes, n.node)
+		for i := len(n.children) - 1; i >= 0; i-- {
+			stack = append(stack, n.children[i])
+		}
+	}
+	return nodes
+}
+
+// GenerateTree builds a tree returning the return type of the primitive set
+func (g *PTC2) GenerateTree() *PrimitiveTree {
+	return NewPrimitiveTree(g.Generate(g.ps, g.ps.RetType))
+}
+
+// expansions returns the primitives the slot can be expanded with
+func (g *PTC2) expansions(ps *PrimitiveSet, report *TypeReport, slot *ptcNode) []*Primitive {
+	if slot.depth >= g.MaxDepth {
+		return nil
+	}
+	return report.completable(ps, slot.type_, false)
+}
+
+// close fills the slot with a terminal, types without terminals are completed
+// with the primitives closing the subtree the fastest
+func (g *PTC2) close(ps *PrimitiveSet, report *TypeReport, slot *ptcNode, r *rand.Rand) {
+	if ps.hasTerminal(slot.type_) {
+		slot.node = ps.randomTerminal(slot.type_, r)
+		return
+	}
+	prims := report.completable(ps, slot.type_, true)
+	if len(prims) == 0 {
+		panic(fmt.Sprintf("No terminal or primitive can complete type: %s", slot.type_))
+	}
+	for _, child := range g.expand(ps, slot, prims, r) {
+		g.close(ps, report, child, r)
+	}
+}
+
+// expand places a weighted random primitive into the slot and returns the
+// slots of its arguments, prims has to contain a positive weight
+func (g *PTC2) expand(ps *PrimitiveSet, slot *ptcNode, prims []*Primitive, r *rand.Rand) []*ptcNode {
+	prim, _ := weightedChoice(ps, prims, r)
+	slot.node = prim
+	for _, t := range prim.argTypes {
+		slot.children = append(slot.children, &ptcNode{type_: t, depth: slot.depth + 1})
+	}
+	return slot.children
+}
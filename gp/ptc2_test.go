@@ -0,0 +1,52 @@
+package gp
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPTC2Sizes(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	r := rand.New(rand.NewSource(17))
+	g := NewPTC2(ps, UniformSize(1, 30), 20, r)
+	counts := map[int]int{}
+	for i := 0; i < 500; i++ {
+		tree := g.GenerateTree()
+		assert.NoError(t, checkNodes(tree.Nodes(), ps.RetType))
+		// prog3 adds at most 2 open slots more than requested
+		assert.LessOrEqual(t, len(tree.Nodes()), 32)
+		counts[len(tree.Nodes())/10]++
+	}
+	// sizes follow the distribution instead of piling up at small trees
+	assert.Greater(t, counts[0], 100)
+	assert.Greater(t, counts[1], 100)
+	assert.Greater(t, counts[2], 100)
+}
+
+func TestPTC2Typed(t *testing.T) {
+	ps := getPrimitiveSet()
+	r := rand.New(rand.NewSource(17))
+	g := NewPTC2(ps, UniformSize(1, 12), 3, r)
+	for i := 0; i < 50; i++ {
+		tree := g.GenerateTree()
+		assert.NoError(t, checkNodes(tree.Nodes(), ps.RetType))
+		assert.LessOrEqual(t, tree.Height(), 3)
+		mustCompile(t, tree)
+	}
+	assert.Equal(t, []Node{term1}, NewPTC2(ps, UniformSize(1, 1), 3, r).GenerateTree().Nodes())
+}
+
+func TestPTC2Weights(t *testing.T) {
+	ps := getFuncPrimitiveSet()
+	r := rand.New(rand.NewSource(17))
+	g := NewPTC2(ps, UniformSize(10, 20), 10, r)
+	ps.SetWeight("prog3", 0)
+	ps.SetWeight("prog2", 0)
+	for i := 0; i < 20; i++ {
+		for _, n := range g.GenerateTree().Nodes() {
+			assert.Contains(t, []string{"if_food_ahead", "move_forward", "turn_left", "turn_right"}, n.Name())
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package gp
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// IndividualFactory builds a concrete Individual from a decoded tree and fitness
+type IndividualFactory func(*PrimitiveTree, *Fitness) Individual
+
+// NodeKind tells which kind of node an EncodedNode is, names are only unique
+// per kind and type
+type NodeKind string
+
+const (
+	KindPrimitive NodeKind = "primitive"
+	KindTerminal  NodeKind = "terminal"
+	KindEphemeral NodeKind = "ephemeral"
+)
+
+type EncodedNode struct {
+	Name  string   `json:"name"`
+	Kind  NodeKind `json:"kind,omitempty"`  // empty matches any kind
+	Value string   `json:"value,omitempty"` // printed value of ephemerals
+}
+
+// EncodedTree is the serializable form of a tree. Nodes can only be resolved
+// against a primitive set, so PrimitiveTree has no JSON methods of its own:
+// encode it with EncodeTree and decode it with EncodedTree.Decode.
+type EncodedTree struct {
+	Nodes []EncodedNode `json:"nodes"`
+}
+
+type EncodedFitness struct {
+	Weights []float32 `json:"weights"`
+	WValues []float32 `json:"wvalues"`
+}
+
+type EncodedIndividual struct {
+	Tree    EncodedTree    `json:"tree"`
+	Fitness EncodedFitness `json:"fitness"`
+}
+
+type EncodedPopulation struct {
+	Individuals []EncodedIndividual `json:"individuals"`
+}
+
+func EncodeTree(pt *PrimitiveTree) EncodedTree {
+	nodes := make([]EncodedNode, len(pt.stack))
+	for i, n := range pt.stack {
+		nodes[i] = EncodedNode{Name: n.Name()}
+		switch n.(type) {
+		case *Primitive:
+			nodes[i].Kind = KindPrimitive
+		case *Terminal:
+			nodes[i].Kind = KindTerminal
+		case *Ephemeral:
+			nodes[i].Kind = KindEphemeral
+			nodes[i].Value = n.Str(nil)
+		}
+	}
+	return EncodedTree{Nodes: nodes}
+}
+
+// Decode resolves every node by kind and name against the type expected by
+// its parent, so nodes sharing a name across types decode to the right one
+func (et EncodedTree) Decode(ps *PrimitiveSet) (*PrimitiveTree, error) {
+	nodes := make([]Node, len(et.Nodes))
+	stack := []Type{ps.RetType}
+	for i, en := range et.Nodes {
+		if len(stack) == 0 {
+			return nil, errors.New(fmt.Sprintf("unexpected node %s at index %d after complete tree", en.Name, i))
+		}
+		var expected Type
+		stack, expected = Pop(stack)
+		node, err := ps.decodeNode(en, expected)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("ephemeral %s at index %d: %s", en.Name, i, err.Error()))
+		}
+		if node == nil {
+			if other, _ := ps.decodeNode(en, reflect.Invalid); other != nil {
+				return nil, errors.New(fmt.Sprintf("node %s at index %d returns %v, expected %v", en.Name, i, other.Ret(), expected))
+			}
+			return nil, errors.New(fmt.Sprintf("unknown node %s at index %d", en.Name, i))
+		}
+		nodes[i] = node
+		if prim, ok := node.(*Primitive); ok {
+			for j := len(prim.argTypes) - 1; j >= 0; j-- {
+				stack = append(stack, prim.argTypes[j])
+			}
+		}
+	}
+	if err := checkNodes(nodes, ps.RetType); err != nil {
+		return nil, err
+	}
+	return NewPrimitiveTree(nodes), nil
+}
+
+// decodeNode finds the node of the encoded kind and name that can be used
+// where type_ is expected, reflect.Invalid matches any type. Only the value of
+// an ephemeral can fail to decode.
+func (ps *PrimitiveSet) decodeNode(en EncodedNode, type_ Type) (Node, error) {
+	if en.Kind == "" || en.Kind == KindPrimitive {
+		if prim := ps.findPrimitive(en.Name, type_); prim != nil {
+			return prim, nil
+		}
+	}
+	if en.Kind == "" || en.Kind == KindTerminal {
+		if term := ps.findTerminal(en.Name, type_); term != nil {
+			return term, nil
+		}
+	}
+	if en.Kind == "" || en.Kind == KindEphemeral {
+		if eph := ps.findEphemeral(en.Name, type_); eph != nil {
+			value, err := parseLiteral(en.Value, eph.Ret())
+			if err != nil {
+				return nil, err
+			}
+			return eph.WithValue(value), nil
+		}
+	}
+	return nil, nil
+}
+
+func EncodeFitness(f *Fitness) EncodedFitness {
+	return EncodedFitness{
+		Weights: append([]float32{}, f.weights...),
+		WValues: append([]float32{}, f.wvalues...),
+	}
+}
+
+func (ef EncodedFitness) Decode() (*Fitness, error) {
+	if len(ef.WValues) > 0 && len(ef.WValues) != len(ef.Weights) {
+		return nil, errors.New("values and weights must have the same size")
+	}
+	fit, err := NewFitness(append([]float32{}, ef.Weights...))
+	if err != nil {
+		return nil, err
+	}
+	fit.wvalues = append([]float32{}, ef.WValues...)
+	return fit, nil
+}
+
+func EncodeIndividual(ind Individual) EncodedIndividual {
+	return EncodedIndividual{
+		Tree:    EncodeTree(ind.Tree()),
+		Fitness: EncodeFitness(ind.Fitness()),
+	}
+}
+
+func (ei EncodedIndividual) Decode(ps *PrimitiveSet, factory IndividualFactory) (Individual, error) {
+	tree, err := ei.Tree.Decode(ps)
+	if err != nil {
+		return nil, err
+	}
+	fit, err := ei.Fitness.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return factory(tree, fit), nil
+}
+
+func EncodePopulation(inds []Individual) EncodedPopulation {
+	encoded := make([]EncodedIndividual, len(inds))
+	for i, ind := range inds {
+		encoded[i] = EncodeIndividual(ind)
+	}
+	return EncodedPopulation{Individuals: encoded}
+}
+
+func (ep EncodedPopulation) Decode(ps *PrimitiveSet, factory IndividualFactory) ([]Individual, error) {
+	inds := make([]Individual, len(ep.Individuals))
+	for i, ei := range ep.Individuals {
+		ind, err := ei.Decode(ps, factory)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("individual %d: %s", i, err.Error()))
+		}
+		inds[i] = ind
+	}
+	return inds, nil
+}
+
+func WritePopulationJSON(w io.Writer, inds []Individual) error {
+	return json.NewEncoder(w).Encode(EncodePopulation(inds))
+}
+
+func ReadPopulationJSON(r io.Reader, ps *PrimitiveSet, factory IndividualFactory) ([]Individual, error) {
+	var ep EncodedPopulation
+	if err := json.NewDecoder(r).Decode(&ep); err != nil {
+		return nil, err
+	}
+	return ep.Decode(ps, factory)
+}
+
+func WritePopulationGob(w io.Writer, inds []Individual) error {
+	return gob.NewEncoder(w).Encode(EncodePopulation(inds))
+}
+
+func ReadPopulationGob(r io.Reader, ps *PrimitiveSet, factory IndividualFactory) ([]Individual, error) {
+	var ep EncodedPopulation
+	if err := gob.NewDecoder(r).Decode(&ep); err != nil {
+		return nil, err
+	}
+	return ep.Decode(ps, factory)
+}
+
+func (f *Fitness) MarshalJSON() ([]byte, error) {
+	return json.Marshal(EncodeFitness(f))
+}
+
+func (f *Fitness) UnmarshalJSON(data []byte) error {
+	var ef EncodedFitness
+	if err := json.Unmarshal(data, &ef); err != nil {
+		return err
+	}
+	fit, err := ef.Decode()
+	if err != nil {
+		return err
+	}
+	*f = *fit
+	return nil
+}
+
+func (f *Fitness) GobEncode() ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(EncodeFitness(f)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (f *Fitness) GobDecode(data []byte) error {
+	var ef EncodedFitness
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&ef); err != nil {
+		return err
+	}
+	fit, err := ef.Decode()
+	if err != nil {
+		return err
+	}
+	*f = *fit
+	return nil
+}
+
+// checkNodes verifies that the prefix list forms a single complete tree
+// where every argument matches the type expected by its parent
+func checkNodes(nodes []Node, type_ Type) error {
+	if len(nodes) == 0 {
+		return errors.New("empty tree")
+	}
+	stack := []Type{type_}
+	for i, n := range nodes {
+		if len(stack) == 0 {
+			return errors.New(fmt.Sprintf("unexpected node %s at index %d after complete tree", n.Name(), i))
+		}
+		var expected Type
+		stack, expected = Pop(stack)
+		if !IsSubtype(n.Ret(), expected) {
+			return errors.New(fmt.Sprintf("node %s at index %d returns %v, expected %v", n.Name(), i, n.Ret(), expected))
+		}
+		if prim, ok := n.(*Primitive); ok {
+			for j := len(prim.argTypes) - 1; j >= 0; j-- {
+				stack = append(stack, prim.argTypes[j])
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return errors.New(fmt.Sprintf("incomplete tree, %d arguments missing", len(stack)))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package gp
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func individualFactory(tree *PrimitiveTree, fit *Fitness) Individual {
+	return &IndividualImpl{tree: tree, fitness: fit}
+}
+
+func TestEncodeTree(t *testing.T) {
+	ps := getPrimitiveSet()
+	tree := NewPrimitiveTree(getValidNodes())
+	data, err := json.Marshal(EncodeTree(tree))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"nodes":[{"name":"prim1","kind":"primitive"},{"name":"term1","kind":"terminal"},{"name":"prim2","kind":"primitive"},{"name":"term2","kind":"terminal"},{"name":"term1","kind":"terminal"}]}`, string(data))
+
+	var et EncodedTree
+	assert.NoError(t, json.Unmarshal(data, &et))
+	decoded, err := et.Decode(ps)
+	assert.NoError(t, err)
+	assert.Equal(t, tree.Nodes(), decoded.Nodes())
+}
+
+func TestDecodeTreeErrors(t *testing.T) {
+	ps := getPrimitiveSet()
+	encode := func(names ...string) EncodedTree {
+		et := EncodedTree{}
+		for _, n := range names {
+			et.Nodes = append(et.Nodes, EncodedNode{Name: n})
+		}
+		return et
+	}
+	for _, tc := range []struct {
+		tree EncodedTree
+		msg  string
+	}{
+		{encode(), "empty tree"},
+		{encode("prim1", "term1", "prim3"), "unknown node prim3 at index 2"},
+		{encode("prim1", "term1"), "incomplete tree, 1 arguments missing"},
+		{encode("prim1", "term2", "term1"), "node term2 at index 1 returns string, expected int"},
+		{encode("term1", "term1"), "unexpected node term1 at index 1 after complete tree"},
+	} {
+		_, err := tc.tree.Decode(ps)
+		assert.EqualError(t, err, tc.msg)
+	}
+}
+
+func TestFitnessJSON(t *testing.T) {
+	fit, _ := NewFitness([]float32{-1, 2})
+	fit.SetValues([]float32{3, 4})
+	data, err := json.Marshal(fit)
+	assert.NoError(t, err)
+
+	decoded := &Fitness{}
+	assert.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, fit, decoded)
+	assert.Equal(t, []float32{3, 4}, decoded.GetValues())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"weights":[1],"wvalues":[1,2]}`), decoded))
+}
+
+func TestPopulationRoundTrip(t *testing.T) {
+	ps := getPrimitiveSet()
+	r := rand.New(rand.NewSource(8))
+	inds := generateInds(10, 3, 2, ps, r)
+	inds[0].Fitness().DelValues()
+
+	for name, codec := range map[string]struct {
+		write func(*bytes.Buffer, []Individual) error
+		read  func(*bytes.Buffer) ([]Individual, error)
+	}{
+		"json": {
+			func(b *bytes.Buffer, inds []Individual) error { return WritePopulationJSON(b, inds) },
+			func(b *bytes.Buffer) ([]Individual, error) { return ReadPopulationJSON(b, ps, individualFactory) },
+		},
+		"gob": {
+			func(b *bytes.Buffer, inds []Individual) error { return WritePopulationGob(b, inds) },
+			func(b *bytes.Buffer) ([]Individual, error) { return ReadPopulationGob(b, ps, individualFactory) },
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			assert.NoError(t, codec.write(&b, inds))
+			decoded, err := codec.read(&b)
+			assert.NoError(t, err)
+			assert.Len(t, decoded, len(inds))
+			assert.False(t, decoded[0].Fitness().Valid())
+			for i := range inds {
+				assert.Equal(t, inds[i].Tree().Nodes(), decoded[i].Tree().Nodes())
+				assert.Equal(t, inds[i].Fitness().GetWValues(), decoded[i].Fitness().GetWValues())
+				assert.Equal(t, inds[i].Fitness().GetWeights(), decoded[i].Fitness().GetWeights())
+			}
+		})
+	}
+}
+
+func TestEncodeTreeEphemeral(t *testing.T) {
+	ps := getPrimitiveSet()
+	ps.AddEphemeralConstant("rand_float", reflect.Float64, func(r *rand.Rand) PrimitiveArgs { return r.Float64() })
+	ps.AddPrimitive(NewPrimitive("round", func(a ...PrimitiveArgs) PrimitiveArgs {
+		return int(a[0].(float64) * 100)
+	}, []Type{reflect.Float64}, reflect.Int))
+	tree, err := ParseTree(`prim1(round(0.125), "hello")`, ps)
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(EncodeTree(tree))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `{"name":"rand_float","kind":"ephemeral","value":"0.125"}`)
+	var et EncodedTree
+	assert.NoError(t, json.Unmarshal(data, &et))
+	decoded, err := et.Decode(ps)
+	assert.NoError(t, err)
+	assert.Equal(t, tree.String(), decoded.String())
+	assert.Equal(t, 12*5, mustCompile(t, decoded))
+
+	et.Nodes[2].Value = "abc"
+	_, err = et.Decode(ps)
+	assert.EqualError(t, err, "ephemeral rand_float at index 2: invalid float64 literal abc")
+}
+
+func TestDecodeTreeSharedNames(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.String)
+	ps.AddPrimitive(prim2)
+	ps.AddTerminal(NewTerminal("x", reflect.Int, 4))
+	ps.AddTerminal(NewTerminal("x", reflect.String, "hello"))
+	ps.AddEphemeralConstant("c", reflect.Int, func(r *rand.Rand) PrimitiveArgs { return r.Intn(10) })
+	ps.AddEphemeralConstant("c", reflect.String, func(r *rand.Rand) PrimitiveArgs { return "abc" })
+	tree, err := ParseTree(`prim2(x, x)`, ps)
+	assert.NoError(t, err)
+	et := EncodeTree(tree)
+	for i := 0; i < 10; i++ {
+		decoded, err := et.Decode(ps)
+		assert.NoError(t, err)
+		assert.Equal(t, tree.Nodes(), decoded.Nodes())
+		assert.Equal(t, "hellohellohellohello", mustCompile(t, decoded))
+	}
+
+	tree, err = ParseTree(`prim2("ab", 2)`, ps)
+	assert.NoError(t, err)
+	decoded, err := EncodeTree(tree).Decode(ps)
+	assert.NoError(t, err)
+	assert.Equal(t, "abab", mustCompile(t, decoded))
+}
@@ -0,0 +1,170 @@
+package gp
+
+import "reflect"
+
+// Type is the type of the values returned and taken by nodes, it is one of
+//   - a reflect.Kind, the coarse default where e.g. all functions are reflect.Func
+//   - a reflect.Type, telling apart named types and function signatures
+//   - a *TypeTag, a user-defined type that can be a subtype of another tag
+//
+// Predeclared types like int or string given as reflect.Type are stored as
+// their kind, so they mix with nodes typed by kind. See IsSubtype for the
+// types that fit where another one is expected.
+type Type any
+
+// TypeTag is a user-defined type, values of a tag are not checked by
+// Primitive.Eval. A tag is a subtype of its parent.
+type TypeTag struct {
+	name   string
+	parent *TypeTag
+}
+
+func NewTypeTag(name string, parent *TypeTag) *TypeTag {
+	return &TypeTag{
+		name:   name,
+		parent: parent,
+	}
+}
+
+func (t *TypeTag) String() string {
+	return t.name
+}
+
+// TypeFor returns the reflect.Type of T, it also works for interface types
+func TypeFor[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// IsSubtype tells if a node returning sub can be used where super is
+// expected. It holds for equal types, for anything where reflect.Interface is
+// expected, for a reflect.Type where its kind is expected, for a reflect.Type
+// implementing an expected interface type and for the descendants of a tag.
+func IsSubtype(sub Type, super Type) bool {
+	sub, super = normalizeType(sub), normalizeType(super)
+	if sub == super || super == reflect.Interface {
+		return true
+	}
+	switch s := super.(type) {
+	case reflect.Kind:
+		if t, ok := sub.(reflect.Type); ok {
+			return t.Kind() == s
+		}
+	case reflect.Type:
+		if t, ok := sub.(reflect.Type); ok && s.Kind() == reflect.Interface {
+			return t.Implements(s)
+		}
+	case *TypeTag:
+		if t, ok := sub.(*TypeTag); ok {
+			for p := t.parent; p != nil; p = p.parent {
+				if p == s {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func normalizeType(t Type) Type {
+	rt, ok := t.(reflect.Type)
+	if !ok {
+		return t
+	}
+	if rt.PkgPath() == "" && rt.Name() == rt.Kind().String() {
+		return rt.Kind()
+	}
+	if rt.Kind() == reflect.Interface && rt.Name() == "" && rt.NumMethod() == 0 {
+		return reflect.Interface
+	}
+	return rt
+}
+
+func normalizeTypes(types []Type) []Type {
+	res := make([]Type, len(types))
+	for i, t := range types {
+		res[i] = normalizeType(t)
+	}
+	return res
+}
+
+// kindOf returns the kind of the values of a type, reflect.Invalid for tags
+func kindOf(t Type) reflect.Kind {
+	switch tt := t.(type) {
+	case reflect.Kind:
+		return tt
+	case reflect.Type:
+		return tt.Kind()
+	}
+	return reflect.Invalid
+}
+
+// acceptsValue checks a value passed where t is expected, values of tags are
+// always accepted
+func acceptsValue(t Type, value any) bool {
+	if value == nil {
+		return kindOf(t) == reflect.Interface || kindOf(t) == reflect.Invalid
+	}
+	switch tt := t.(type) {
+	case reflect.Kind:
+		return tt == reflect.Interface || reflect.TypeOf(value).Kind() == tt
+	case reflect.Type:
+		return reflect.TypeOf(value).AssignableTo(tt)
+	}
+	return true
+}
+
+func containsType(types []Type, t Type) bool {
+	for _, other := range types {
+		if other == t {
+			return true
+		}
+	}
+	return false
+}
+
+// subtypesOf returns the keys of the map that are subtypes of t, t itself
+// first and the rest in the given order. Types that print the same (like
+// tags with the same name) are still told apart, so draws from the result
+// are reproducible.
+func subtypesOf[V any](m map[Type]V, order []Type, t Type) []Type {
+	var res []Type
+	if _, ok := m[t]; ok {
+		res = append(res, t)
+	}
+	for _, k := range order {
+		if _, ok := m[k]; ok && k != t && IsSubtype(k, t) {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// registerType keeps the order in which the types of the nodes were added
+func (ps *PrimitiveSet) registerType(t Type) {
+	if !containsType(ps.types, t) {
+		ps.types = append(ps.types, t)
+	}
+}
+
+// primitivesFor returns the primitives that can be used where t is expected
+func (ps *PrimitiveSet) primitivesFor(t Type) []*Primitive {
+	var res []*Primitive
+	for _, k := range subtypesOf(ps.Primitives, ps.types, t) {
+		res = append(res, ps.Primitives[k]...)
+	}
+	return res
+}
+
+// terminalsFor returns the terminals and ephemerals that can be used where t
+// is expected
+func (ps *PrimitiveSet) terminalsFor(t Type) ([]*Terminal, []*Ephemeral) {
+	var terms []*Terminal
+	for _, k := range subtypesOf(ps.Terminals, ps.types, t) {
+		terms = append(terms, ps.Terminals[k]...)
+	}
+	var ephs []*Ephemeral
+	for _, k := range subtypesOf(ps.Ephemerals, ps.types, t) {
+		ephs = append(ephs, ps.Ephemerals[k]...)
+	}
+	return terms, ephs
+}
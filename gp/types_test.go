@@ -0,0 +1,123 @@
+package gp
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type action func()
+type sensor func() bool
+
+func getActionPrimitiveSet() *PrimitiveSet {
+	var noop PrimitiveFunc = func(_ ...PrimitiveArgs) PrimitiveArgs { return action(func() {}) }
+	actionType, sensorType := TypeFor[action](), TypeFor[sensor]()
+	ps := NewPrimitiveSet([]Type{}, actionType)
+	ps.AddPrimitive(NewPrimitive("seq", noop, []Type{actionType, actionType}, actionType))
+	ps.AddPrimitive(NewPrimitive("when", noop, []Type{sensorType, actionType, actionType}, actionType))
+	ps.AddTerminal(NewTerminal("move", actionType, action(func() {})))
+	ps.AddTerminal(NewTerminal("food_ahead", sensorType, sensor(func() bool { return true })))
+	return ps
+}
+
+func TestIsSubtype(t *testing.T) {
+	animal := NewTypeTag("animal", nil)
+	dog := NewTypeTag("dog", animal)
+	puppy := NewTypeTag("puppy", dog)
+	for _, tc := range []struct {
+		sub, super Type
+		expected   bool
+	}{
+		{reflect.Int, reflect.Int, true},
+		{reflect.Int, reflect.String, false},
+		{TypeFor[int](), reflect.Int, true},
+		{reflect.Int, reflect.Interface, true},
+		{TypeFor[action](), reflect.Func, true},
+		{reflect.Func, TypeFor[action](), false},
+		{TypeFor[action](), TypeFor[sensor](), false},
+		{TypeFor[*TypeTag](), TypeFor[fmt.Stringer](), true},
+		{TypeFor[action](), TypeFor[fmt.Stringer](), false},
+		{puppy, animal, true},
+		{animal, dog, false},
+		{dog, NewTypeTag("dog", animal), false},
+	} {
+		assert.Equal(t, tc.expected, IsSubtype(tc.sub, tc.super), "%v <: %v", tc.sub, tc.super)
+	}
+	assert.Equal(t, reflect.Int, NewTerminal("one", TypeFor[int](), 1).Ret())
+	assert.Equal(t, reflect.Interface, NewTerminal("nil", TypeFor[any](), nil).Ret())
+}
+
+func TestFineTypesGeneration(t *testing.T) {
+	ps := getActionPrimitiveSet()
+	r := rand.New(rand.NewSource(22))
+	mutator := NewUniformMutator(ps, func(ps *PrimitiveSet, type_ Type) []Node {
+		return GenerateTree(ps, 0, 2, GenGrow, type_, r).Nodes()
+	}, r)
+	trees := []*PrimitiveTree{}
+	for i := 0; i < 20; i++ {
+		tree := GenerateTree(ps, 1, 4, GenGrow, ps.RetType, r)
+		assert.NoError(t, checkNodes(tree.Nodes(), ps.RetType))
+		tree, _ = mutator.Mutate(tree)
+		tree, _ = NewNodeReplacementMutator(ps, r).Mutate(tree)
+		assert.NoError(t, checkNodes(tree.Nodes(), ps.RetType))
+		trees = append(trees, tree)
+	}
+	for i := 1; i < len(trees); i += 2 {
+		child1, child2, _ := CXOnePoint(*trees[i-1], *trees[i], r, 0)
+		assert.NoError(t, checkNodes(child1.Nodes(), ps.RetType))
+		assert.NoError(t, checkNodes(child2.Nodes(), ps.RetType))
+	}
+
+	// a sensor is not an action even if both are functions
+	_, err := ParseTree("seq(move, food_ahead)", ps)
+	assert.Equal(t, &ParseError{Pos: 10, Msg: "terminal food_ahead is gp.sensor, expected gp.action"}, err)
+	_, err = ps.Primitives[ps.RetType][0].Eval([]PrimitiveArgs{action(func() {}), sensor(func() bool { return false })})
+	assert.Error(t, err)
+}
+
+func TestSubtypeTags(t *testing.T) {
+	animal := NewTypeTag("animal", nil)
+	dog := NewTypeTag("dog", animal)
+	var pair PrimitiveFunc = func(a ...PrimitiveArgs) PrimitiveArgs { return fmt.Sprintf("%v&%v", a[0], a[1]) }
+	ps := NewPrimitiveSet([]Type{}, animal)
+	ps.AddPrimitive(NewPrimitive("pair", pair, []Type{animal, animal}, animal))
+	ps.AddPrimitive(NewPrimitive("pack", pair, []Type{dog, dog}, dog))
+	ps.AddTerminal(NewTerminal("rex", dog, "rex"))
+
+	// dogs are the only terminals but they complete animals
+	report, err := ps.Validate()
+	assert.NoError(t, err)
+	assert.Equal(t, []Type{animal, dog}, report.Types)
+	assert.Empty(t, report.MissingTerminals)
+
+	r := rand.New(rand.NewSource(22))
+	for i := 0; i < 10; i++ {
+		tree := GenerateTree(ps, 1, 3, GenFull, ps.RetType, r)
+		assert.NoError(t, checkNodes(tree.Nodes(), ps.RetType))
+		mustCompile(t, tree)
+	}
+	tree, err := ParseTree("pair(pack(rex, rex), rex)", ps)
+	assert.NoError(t, err)
+	assert.Equal(t, "rex&rex&rex", mustCompile(t, tree))
+	_, err = ParseTree("pack(pair(rex, rex), rex)", ps)
+	assert.Equal(t, &ParseError{Pos: 5, Msg: "primitive pair returns animal, expected dog"}, err)
+}
+
+func TestSubtypesRegistrationOrder(t *testing.T) {
+	animal := NewTypeTag("animal", nil)
+	ps := NewPrimitiveSet([]Type{}, animal)
+	var terms []*Terminal
+	for i := 0; i < 5; i++ {
+		// tags printing the same are told apart by the order they were added
+		term := NewTerminal(fmt.Sprintf("pet%d", i), NewTypeTag("pet", animal), i)
+		ps.AddTerminal(term)
+		terms = append(terms, term)
+	}
+	for i := 0; i < 10; i++ {
+		found, _ := ps.terminalsFor(animal)
+		assert.Equal(t, terms, found)
+	}
+}
@@ -2,7 +2,6 @@ package gp
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
 
 	"golang.org/x/exp/constraints"
@@ -21,7 +20,7 @@ type nodeInterface struct {
 
 type stackItem struct {
 	i int
-	t reflect.Kind
+	t Type
 }
 
 func Max(a int, b int) int {
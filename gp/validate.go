@@ -0,0 +1,140 @@
+package gp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TypeReport is the type-reachability analysis of a PrimitiveSet
+type TypeReport struct {
+	Types             []Type       // types that can be requested starting from RetType
+	MissingTerminals  []Type       // reachable types without terminals or ephemerals of positive weight
+	MissingPrimitives []Type       // reachable types without primitives
+	MinDepth          map[Type]int // height of the smallest complete subtree of a type, missing if it can not be completed
+}
+
+// Incomplete returns the reachable types for which no complete subtree exists
+func (tr *TypeReport) Incomplete() []Type {
+	var res []Type
+	for _, k := range tr.Types {
+		if _, ok := tr.MinDepth[k]; !ok {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// Validate analyses which types can be requested from RetType through the
+// argument types of the primitives. It returns an error if some of them can
+// never be completed, trees requesting such a type can not be generated.
+// Nodes with a weight of 0 are never drawn so they do not count.
+func (ps *PrimitiveSet) Validate() (*TypeReport, error) {
+	report := ps.analyse(ps.RetType)
+	if incomplete := report.Incomplete(); len(incomplete) > 0 {
+		names := make([]string, len(incomplete))
+		for i, k := range incomplete {
+			names[i] = fmt.Sprint(k)
+		}
+		return report, errors.New(fmt.Sprintf("no complete subtree for types: %s", strings.Join(names, ", ")))
+	}
+	return report, nil
+}
+
+// report returns the analysis of the type, it is cached until a node is added
+// to the primitive set or a weight is changed. Changes made to the node maps directly are not seen.
+func (ps *PrimitiveSet) report(type_ Type) *TypeReport {
+	ps.reportsMu.Lock()
+	defer ps.reportsMu.Unlock()
+	if report, ok := ps.reports[type_]; ok {
+		return report
+	}
+	if ps.reports == nil {
+		ps.reports = map[Type]*TypeReport{}
+	}
+	report := ps.analyse(type_)
+	ps.reports[type_] = report
+	return report
+}
+
+func (ps *PrimitiveSet) invalidateReports() {
+	ps.reportsMu.Lock()
+	defer ps.reportsMu.Unlock()
+	ps.reports = nil
+}
+
+// analyse runs the reachability analysis starting from the given type, the
+// nodes of a type include the ones of its subtypes
+func (ps *PrimitiveSet) analyse(type_ Type) *TypeReport {
+	report := &TypeReport{MinDepth: map[Type]int{}}
+	queue := []Type{type_}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		if containsType(report.Types, k) {
+			continue
+		}
+		report.Types = append(report.Types, k)
+		if !ps.hasTerminal(k) {
+			report.MissingTerminals = append(report.MissingTerminals, k)
+		}
+		prims := positiveWeight(ps, ps.primitivesFor(k))
+		if len(prims) == 0 {
+			report.MissingPrimitives = append(report.MissingPrimitives, k)
+		}
+		for _, p := range prims {
+			queue = append(queue, p.argTypes...)
+		}
+	}
+
+	for _, k := range report.Types {
+		if ps.hasTerminal(k) {
+			report.MinDepth[k] = 0
+		}
+	}
+	for updated := true; updated; {
+		updated = false
+		for _, k := range report.Types {
+			for _, p := range positiveWeight(ps, ps.primitivesFor(k)) {
+				depth, ok := report.primitiveDepth(p)
+				if old, found := report.MinDepth[k]; ok && (!found || depth < old) {
+					report.MinDepth[k] = depth
+					updated = true
+				}
+			}
+		}
+	}
+	return report
+}
+
+// primitiveDepth is the height of the smallest complete subtree rooted at p
+func (tr *TypeReport) primitiveDepth(p *Primitive) (int, bool) {
+	depth := 0
+	for _, t := range p.argTypes {
+		d, ok := tr.MinDepth[t]
+		if !ok {
+			return 0, false
+		}
+		depth = Max(depth, d)
+	}
+	return depth + 1, true
+}
+
+// completable returns the primitives of the type whose arguments can all be
+// completed, if shortest is set only the ones closing the subtree the fastest
+func (tr *TypeReport) completable(ps *PrimitiveSet, type_ Type, shortest bool) []*Primitive {
+	var res []*Primitive
+	for _, p := range positiveWeight(ps, ps.primitivesFor(type_)) {
+		depth, ok := tr.primitiveDepth(p)
+		if ok && (!shortest || depth == tr.MinDepth[type_]) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// hasTerminal tells if a terminal or ephemeral of the type can be drawn
+func (ps *PrimitiveSet) hasTerminal(type_ Type) bool {
+	terms, ephs := ps.terminalsFor(type_)
+	return len(positiveWeight(ps, terms))+len(positiveWeight(ps, ephs)) > 0
+}
@@ -0,0 +1,98 @@
+package gp
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var itoa = NewPrimitive("itoa", func(a ...PrimitiveArgs) PrimitiveArgs {
+	return fmt.Sprint(a[0].(int))
+}, []Type{reflect.Int}, reflect.String)
+
+func TestValidate(t *testing.T) {
+	report, err := getPrimitiveSet().Validate()
+	assert.NoError(t, err)
+	assert.Equal(t, []Type{reflect.Int, reflect.String}, report.Types)
+	assert.Empty(t, report.MissingTerminals)
+	assert.Empty(t, report.MissingPrimitives)
+	assert.Equal(t, map[Type]int{reflect.Int: 0, reflect.String: 0}, report.MinDepth)
+}
+
+func TestValidateMissingTerminal(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	ps.AddPrimitive(prim1)
+	ps.AddPrimitive(prim2)
+	ps.AddPrimitive(itoa)
+	ps.AddTerminal(term1)
+	report, err := ps.Validate()
+	assert.NoError(t, err)
+	assert.Equal(t, []Type{reflect.String}, report.MissingTerminals)
+	assert.Equal(t, map[Type]int{reflect.Int: 0, reflect.String: 1}, report.MinDepth)
+
+	// strings are closed with itoa instead of panicking
+	r := rand.New(rand.NewSource(18))
+	for i := 0; i < 10; i++ {
+		tree := GenerateTree(ps, 1, 4, GenFull, ps.RetType, r)
+		assert.NoError(t, checkNodes(tree.Nodes(), ps.RetType))
+		assert.LessOrEqual(t, tree.Height(), 4)
+		mustCompile(t, tree)
+	}
+}
+
+func TestValidateIncomplete(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	ps.AddPrimitive(prim1)
+	ps.AddTerminal(term1)
+	report, err := ps.Validate()
+	assert.EqualError(t, err, "no complete subtree for types: string")
+	assert.Equal(t, []Type{reflect.String}, report.MissingTerminals)
+	assert.Equal(t, []Type{reflect.String}, report.MissingPrimitives)
+	assert.Equal(t, []Type{reflect.String}, report.Incomplete())
+
+	// prim1 can never be completed so it is not used
+	r := rand.New(rand.NewSource(18))
+	tree := GenerateTree(ps, 2, 3, GenFull, ps.RetType, r)
+	assert.Equal(t, []Node{term1}, tree.Nodes())
+
+	assert.PanicsWithValue(t, "No terminal or primitive can complete type: string", func() {
+		GenerateTree(ps, 2, 3, GenFull, reflect.String, r)
+	})
+}
+
+func TestReportCache(t *testing.T) {
+	ps := getPrimitiveSet()
+	report := ps.report(ps.RetType)
+	assert.Same(t, report, ps.report(ps.RetType))
+	assert.NotSame(t, report, ps.report(reflect.String))
+
+	ps.AddTerminal(NewTerminal("term3", reflect.Int, 7))
+	updated := ps.report(ps.RetType)
+	assert.NotSame(t, report, updated)
+	assert.Equal(t, report, updated)
+}
+
+func TestValidateWeights(t *testing.T) {
+	ps := NewPrimitiveSet([]Type{}, reflect.Int)
+	ps.AddPrimitive(NewPrimitive("round", func(a ...PrimitiveArgs) PrimitiveArgs {
+		return int(a[0].(float64))
+	}, []Type{reflect.Float64}, reflect.Int))
+	ps.AddTerminal(NewTerminal("one", reflect.Int, 1))
+	ps.AddTerminal(NewTerminal("half", reflect.Float64, 0.5))
+	ps.SetWeight("one", 0)
+
+	report, err := ps.Validate()
+	assert.NoError(t, err)
+	assert.Equal(t, []Type{reflect.Int}, report.MissingTerminals)
+	r := rand.New(rand.NewSource(37))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "round(0.5)", GenerateTree(ps, 0, 3, GenGrow, ps.RetType, r).String())
+	}
+
+	ps.SetWeight("half", 0)
+	_, err = ps.Validate()
+	assert.EqualError(t, err, "no complete subtree for types: int, float64")
+}